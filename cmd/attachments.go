@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+// attachmentDownloader saves the attachments a page's Markdown refers to
+// into a directory, and links to them relative to the output file.
+type attachmentDownloader struct {
+	client *confluence.Client
+	dir    string
+	// link is dir as seen from the directory the Markdown is written to.
+	link string
+	// owners maps each saved file name to the page it was saved for, so
+	// same-named attachments of different pages don't overwrite each other.
+	owners map[string]string
+}
+
+func newAttachmentDownloader(client *confluence.Client, dir, outputFile string) (*attachmentDownloader, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating attachments directory: %w", err)
+	}
+
+	link := dir
+	if outputFile != "" {
+		rel, err := filepath.Rel(filepath.Dir(outputFile), dir)
+		if err == nil {
+			link = rel
+		}
+	}
+	return &attachmentDownloader{client: client, dir: dir, link: filepath.ToSlash(link), owners: map[string]string{}}, nil
+}
+
+// Attachment implements markdown.AttachmentResolver.
+func (d *attachmentDownloader) Attachment(page *confluence.Page, filename string) (string, error) {
+	name := filepath.Base(filename)
+	if owner, ok := d.owners[name]; ok && owner != page.ID {
+		name = page.ID + "-" + name
+	}
+	d.owners[name] = page.ID
+
+	target := filepath.Join(d.dir, name)
+	info, statErr := os.Stat(target)
+	a, err := d.client.FindAttachment(page.ID, filename)
+	if err != nil {
+		// Keep using a copy saved earlier, e.g. when offline
+		if statErr == nil {
+			return path.Join(d.link, url.PathEscape(name)), nil
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return "", err
+	}
+
+	if statErr != nil || !savedAttachmentCurrent(info, a) {
+		data, err := d.client.DownloadAttachment(a)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return "", err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return "", fmt.Errorf("writing attachment: %w", err)
+		}
+	}
+	return path.Join(d.link, url.PathEscape(name)), nil
+}
+
+// savedAttachmentCurrent reports whether a file saved earlier still holds
+// the attachment's current version: it has the attachment's size and was
+// written after that version was uploaded.
+func savedAttachmentCurrent(info os.FileInfo, a *confluence.Attachment) bool {
+	if a.Extensions.FileSize > 0 && info.Size() != a.Extensions.FileSize {
+		return false
+	}
+	return !info.ModTime().Before(a.Version.When)
+}
+
+// HasAttachment implements markdown.AttachmentResolver.
+func (d *attachmentDownloader) HasAttachment(page *confluence.Page, filename string) bool {
+	_, err := d.client.FindAttachment(page.ID, filename)
+	return err == nil
+}
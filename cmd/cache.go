@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/justinabrahms/confluence-md/internal/cache"
+	"github.com/spf13/cobra"
+)
+
+var pruneOlderThan time.Duration
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Show, prune or clear the response cache",
+	Long: `Manage the cache of Confluence responses in $XDG_CACHE_HOME/confluence-md.
+
+Every GET request is cached per URL and account. Cached responses are
+revalidated with conditional requests, and a page whose version hasn't
+changed is served from the cache without downloading it again. Use
+--no-cache to bypass the cache, or --offline to work from it alone.`,
+}
+
+var cacheShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "List the cached responses",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := &cache.Cache{Dir: cache.DefaultDir()}
+		entries, err := store.Entries()
+		if err != nil {
+			return err
+		}
+
+		var total int64
+		for _, e := range entries {
+			total += e.Size
+		}
+		fmt.Printf("Cache: %s\n", store.Dir)
+		fmt.Printf("Found %d entries, %s\n\n", len(entries), formatSize(total))
+
+		for _, e := range entries {
+			version := ""
+			if e.Version > 0 {
+				version = fmt.Sprintf(" v%d", e.Version)
+			}
+			fmt.Printf("%s  %8s  HTTP %d%s  %s\n", e.Used.Format("2006-01-02 15:04"), formatSize(e.Size), e.Status, version, e.URL)
+			if Debug {
+				fmt.Fprintf(os.Stderr, "[DEBUG]   profile=%s etag=%s stored=%s\n", e.Profile, e.ETag, e.Stored.Format(time.RFC3339))
+			}
+		}
+		return nil
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove cached responses that haven't been used recently",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := &cache.Cache{Dir: cache.DefaultDir()}
+		removed, err := store.Prune(time.Now().Add(-pruneOlderThan))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Removed %d entries\n", removed)
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached response",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := &cache.Cache{Dir: cache.DefaultDir()}
+		removed, err := store.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Removed %d entries\n", removed)
+		return nil
+	},
+}
+
+// formatSize formats a byte count for humans.
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheShowCmd, cachePruneCmd, cacheClearCmd)
+	cachePruneCmd.Flags().DurationVar(&pruneOlderThan, "older-than", 30*24*time.Hour, "Remove entries last used longer ago than this")
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	chunkMode   bool
+	chunkTokens int
+	chunkChars  int
+)
+
+// addChunkFlags registers the flags of the NDJSON chunk output shared by
+// fetch and export.
+func addChunkFlags(c *cobra.Command) {
+	c.Flags().BoolVar(&chunkMode, "chunk", false, "Output heading-aware chunks as NDJSON instead of Markdown")
+	c.Flags().IntVar(&chunkTokens, "chunk-tokens", 500, "Maximum estimated tokens per chunk")
+	c.Flags().IntVar(&chunkChars, "chunk-chars", 0, "Maximum characters per chunk (overrides --chunk-tokens)")
+}
+
+// chunkRecord is one line of --chunk output.
+type chunkRecord struct {
+	PageID     string   `json:"page_id"`
+	Title      string   `json:"title"`
+	URL        string   `json:"url,omitempty"`
+	Breadcrumb []string `json:"breadcrumb"`
+	Index      int      `json:"index"`
+	Text       string   `json:"text"`
+}
+
+// chunkBudget returns the chunk size limit and how to measure it.
+func chunkBudget() (int, markdown.SizeFunc, error) {
+	if chunkChars > 0 {
+		return chunkChars, markdown.CountChars, nil
+	}
+	if chunkTokens <= 0 {
+		return 0, nil, fmt.Errorf("--chunk-tokens must be positive")
+	}
+	return chunkTokens, markdown.EstimateTokens, nil
+}
+
+// writeChunks splits a page's Markdown into chunks and appends them to b as
+// NDJSON.
+func writeChunks(b *strings.Builder, page *confluence.Page, md, baseURL string) error {
+	limit, size, err := chunkBudget()
+	if err != nil {
+		return err
+	}
+
+	url := ""
+	if page.Links.WebUI != "" {
+		url = baseURL + page.Links.WebUI
+	}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	for i, chunk := range markdown.SplitChunks(md, limit, size) {
+		breadcrumb := chunk.Breadcrumb
+		if breadcrumb == nil {
+			breadcrumb = []string{}
+		}
+		record := chunkRecord{
+			PageID:     page.ID,
+			Title:      page.Title,
+			URL:        url,
+			Breadcrumb: breadcrumb,
+			Index:      i,
+			Text:       chunk.Text,
+		}
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("encoding chunk: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/justinabrahms/confluence-md/internal/cache"
+	"github.com/justinabrahms/confluence-md/internal/config"
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+// loadConfig loads the configuration. With --offline no API token is
+// needed, since nothing is sent to Confluence.
+func loadConfig() (*config.Config, error) {
+	if Offline {
+		return config.LoadOffline()
+	}
+	return config.Load()
+}
+
+// newClient creates a Confluence client from the loaded configuration,
+// caching responses unless --no-cache is set.
+func newClient(cfg *config.Config) *confluence.Client {
+	client := confluence.NewClient(cfg.ConfluenceURL, cfg.Email, cfg.APIToken, Debug)
+	client.APIVersion = cfg.APIVersion
+	client.Offline = Offline
+	if !NoCache {
+		store, err := cache.Open(cache.DefaultDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			client.Cache = store
+		}
+	}
+	return client
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/justinabrahms/confluence-md/internal/config"
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bodyFormat     string
+	unknownMacros  string
+	statusEmoji    bool
+	userLinks      bool
+	jiraDetails    bool
+	tableStyle     string
+	layoutRules    bool
+	inlineComments string
+	flavorName     string
+)
+
+// addConversionFlags registers the Markdown conversion flags shared by
+// every command that converts pages.
+func addConversionFlags(c *cobra.Command) {
+	c.Flags().StringVar(&flavorName, "flavor", "gfm", "Markdown dialect to write: gfm, commonmark, obsidian, hugo, mkdocs or docusaurus")
+	c.Flags().StringVar(&bodyFormat, "body-format", string(markdown.BodyFormatStorage), "Page body to convert: storage, view or adf")
+	c.Flags().BoolVar(&statusEmoji, "status-emoji", false, "Prefix status lozenges with an emoji for their colour")
+	c.Flags().BoolVar(&userLinks, "user-links", false, "Link user mentions to their Confluence profile")
+	c.Flags().BoolVar(&jiraDetails, "jira-details", false, "Look up Jira issue summaries and status, and render JQL macros as tables")
+	c.Flags().StringVar(&tableStyle, "tables", string(markdown.TableHTML), "How to render tables with merged cells or block content: html or grid")
+	c.Flags().BoolVar(&layoutRules, "layout-separators", false, "Separate flattened layout columns with horizontal rules")
+	c.Flags().StringVar(&inlineComments, "inline-comments", "drop", "How to render inline comments: drop (keep only the text) or footnotes")
+	c.Flags().StringVar(&unknownMacros, "unknown-macros", string(markdown.UnknownMacroKeep), "How to render macros without a handler: keep (their content), drop or comment")
+}
+
+// newConverter builds a Markdown converter from the conversion flags,
+// followed by any command-specific options. User mentions and, with
+// --jira-details, Jira issues are resolved through client.
+func newConverter(cfg *config.Config, client *confluence.Client, extra ...markdown.Option) (*markdown.Converter, error) {
+	format, err := markdown.ParseBodyFormat(bodyFormat)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := markdown.ParseUnknownMacroPolicy(unknownMacros)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := markdown.ParseTableStyle(tableStyle)
+	if err != nil {
+		return nil, err
+	}
+	flavor, err := markdown.ParseFlavor(flavorName)
+	if err != nil {
+		return nil, err
+	}
+	opts := []markdown.Option{
+		markdown.WithFlavor(flavor),
+		markdown.WithBodyFormat(format),
+		markdown.WithUnknownMacroPolicy(policy),
+		markdown.WithStatusEmoji(statusEmoji),
+		markdown.WithTableStyle(tables),
+		markdown.WithLayoutSeparators(layoutRules),
+		markdown.WithUserResolver(client, userLinks),
+		markdown.WithJira(cfg.JiraURL, nil),
+	}
+	switch inlineComments {
+	case "drop":
+	case "footnotes":
+		opts = append(opts, markdown.WithInlineCommentFootnotes(client))
+	default:
+		return nil, fmt.Errorf("unknown inline comment mode %q (expected drop or footnotes)", inlineComments)
+	}
+	if jiraDetails {
+		if cfg.JiraURL == "" {
+			return nil, fmt.Errorf("--jira-details needs jira_url set (check config file or JIRA_URL env var)")
+		}
+		opts = append(opts, markdown.WithJira(cfg.JiraURL, client.Jira(cfg.JiraURL)))
+	}
+	return markdown.NewConverter(append(opts, extra...)...), nil
+}
+
+// wantsADF reports whether pages must be fetched with their ADF body.
+func wantsADF() bool {
+	return bodyFormat == string(markdown.BodyFormatADF)
+}
+
+// wantsView reports whether pages must be fetched with their view body.
+func wantsView() bool {
+	return bodyFormat == string(markdown.BodyFormatView)
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/diff"
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFrom    int
+	diffTo      int
+	diffWord    bool
+	diffContext int
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [url] [file]",
+	Short: "Show a Markdown diff between page versions or a local file",
+	Long: `Show a unified diff of the Markdown for two versions of a Confluence page,
+or between a page and a local Markdown file.
+
+With only a URL, the latest version is compared to the one before it. Use
+--from and --to to pick versions. When a local file is given, the page
+(at --from, or the latest version) is compared against the file, and --to
+is rejected.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pageURL := args[0]
+		if len(args) == 2 && cmd.Flags().Changed("to") {
+			return fmt.Errorf("--to can't be combined with a local file, which is always the newer side; use --from to pick the page version")
+		}
+
+		// Load configuration
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("loading configuration: %w", err)
+		}
+
+		// Create client
+		client := newClient(cfg)
+		client.ADF = wantsADF()
+		client.View = wantsView()
+
+		converter, err := newConverter(cfg, client)
+		if err != nil {
+			return err
+		}
+
+		if Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Config: URL=%s, Email=%s\n", cfg.ConfluenceURL, cfg.Email)
+			fmt.Fprintf(os.Stderr, "[DEBUG] Diff URL: %s, From: %d, To: %d\n", pageURL, diffFrom, diffTo)
+		}
+
+		pageID, err := confluence.PageIDFromURL(pageURL)
+		if err != nil {
+			return err
+		}
+
+		render := func(version int) (string, *confluence.Page, error) {
+			var page *confluence.Page
+			var err error
+			if version > 0 {
+				page, err = client.GetPageVersion(pageID, version)
+			} else {
+				page, err = client.GetPageByID(pageID)
+			}
+			if err != nil {
+				return "", nil, fmt.Errorf("fetching page: %w", err)
+			}
+			md, err := converter.PageToMarkdown(page, includeMetadata)
+			if err != nil {
+				return "", nil, fmt.Errorf("converting to markdown: %w", err)
+			}
+			return md, page, nil
+		}
+
+		var fromName, toName, fromMD, toMD string
+
+		if len(args) == 2 {
+			// Page against local file
+			md, page, err := render(diffFrom)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(args[1])
+			if err != nil {
+				return fmt.Errorf("reading local file: %w", err)
+			}
+			// Front matter written by "fetch --front-matter" isn't part of the page
+			_, data, err = markdown.SplitFrontMatter(data)
+			if err != nil {
+				return err
+			}
+			fromName = fmt.Sprintf("%s (v%d)", page.Title, page.Version.Number)
+			fromMD = md
+			toName = args[1]
+			toMD = string(data)
+		} else {
+			// Two versions of the page
+			md, page, err := render(diffTo)
+			if err != nil {
+				return err
+			}
+			toName = fmt.Sprintf("%s (v%d)", page.Title, page.Version.Number)
+			toMD = md
+
+			from := diffFrom
+			if from == 0 {
+				from = page.Version.Number - 1
+			}
+			if from < 1 {
+				return fmt.Errorf("page has no earlier version to compare against")
+			}
+			md, page, err = render(from)
+			if err != nil {
+				return err
+			}
+			fromName = fmt.Sprintf("%s (v%d)", page.Title, page.Version.Number)
+			fromMD = md
+		}
+
+		if diffWord {
+			if fromMD != toMD {
+				fmt.Print(diff.Words(fromMD, toMD))
+			}
+			return nil
+		}
+
+		fmt.Print(diff.Unified(fromName, toName, diff.Lines(fromMD), diff.Lines(toMD), diffContext))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().IntVar(&diffFrom, "from", 0, "Version to diff from (default: the version before --to)")
+	diffCmd.Flags().IntVar(&diffTo, "to", 0, "Version to diff to (default: latest)")
+	diffCmd.Flags().BoolVar(&diffWord, "word", false, "Show a word-level diff instead of a unified line diff")
+	diffCmd.Flags().IntVar(&diffContext, "context", 3, "Number of context lines in unified diffs")
+	diffCmd.Flags().BoolVar(&includeMetadata, "include-metadata", false, "Include page metadata in the compared Markdown")
+	addConversionFlags(diffCmd)
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportDir   string
+	exportDepth int
+	exportVault bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [url]",
+	Short: "Export a Confluence page and its descendants",
+	Long: `Export a Confluence page and all pages below it as Markdown files.
+
+Each page is written to <title>.md, with its child pages in a directory of
+the same name next to it. With --chunk, every page is split into chunks and
+written as NDJSON instead.
+
+With --vault, the directory is set up as an Obsidian vault: links between
+pages become wikilinks, attachments are saved to attachments/ and embedded
+with ![[...]], and labels become tags in the front matter.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pageURL := args[0]
+
+		// Load configuration
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("loading configuration: %w", err)
+		}
+
+		// Create client
+		client := newClient(cfg)
+		client.ADF = wantsADF()
+		client.View = wantsView()
+
+		var opts []markdown.Option
+		if expandIncludes {
+			opts = append(opts, markdown.WithIncludes(client, includeDepth))
+		}
+		if exportVault {
+			if chunkMode {
+				return fmt.Errorf("--vault and --chunk can't be combined")
+			}
+			if cmd.Flags().Changed("flavor") && !strings.EqualFold(flavorName, "obsidian") {
+				return fmt.Errorf("--vault writes the obsidian flavor and can't be combined with --flavor %s", flavorName)
+			}
+			flavorName = "obsidian"
+			downloader, err := newAttachmentDownloader(client, filepath.Join(exportDir, "attachments"), "")
+			if err != nil {
+				return err
+			}
+			opts = append(opts, markdown.WithAttachments(downloader))
+		}
+		converter, err := newConverter(cfg, client, opts...)
+		if err != nil {
+			return err
+		}
+
+		if Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Config: URL=%s, Email=%s\n", cfg.ConfluenceURL, cfg.Email)
+			fmt.Fprintf(os.Stderr, "[DEBUG] Exporting URL: %s (depth: %d)\n", pageURL, exportDepth)
+		}
+
+		rootID, err := confluence.PageIDFromURL(pageURL)
+		if err != nil {
+			return err
+		}
+
+		var chunks strings.Builder
+		paths := newExportPaths()
+		count := 0
+		err = walkPageTree(client, rootID, exportDepth, func(page *confluence.Page, ancestors []*confluence.Page) error {
+			md, err := converter.PageToMarkdown(page, includeMetadata)
+			if err != nil {
+				return fmt.Errorf("converting page %q: %w", page.Title, err)
+			}
+			count++
+
+			if chunkMode {
+				return writeChunks(&chunks, page, md, cfg.ConfluenceURL)
+			}
+
+			if frontMatter || exportVault {
+				fm := converter.Flavor().NewFrontMatter(page, cfg.ConfluenceURL)
+				if exportVault {
+					if fm.Tags, err = client.GetLabels(page.ID); err != nil {
+						return fmt.Errorf("fetching labels of %q: %w", page.Title, err)
+					}
+				}
+				header, err := fm.Render()
+				if err != nil {
+					return err
+				}
+				md = header + md
+			}
+			path := filepath.Join(exportDir, paths.file(page, ancestors))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("creating directory: %w", err)
+			}
+			if err := os.WriteFile(path, []byte(md), 0644); err != nil {
+				return fmt.Errorf("writing %s: %w", path, err)
+			}
+			if Debug {
+				fmt.Fprintf(os.Stderr, "[DEBUG] Wrote %s\n", path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if chunkMode {
+			return writeOutput(chunks.String())
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d pages to %s\n", count, exportDir)
+		return nil
+	},
+}
+
+// walkPageTree fetches the page rootID and its descendants depth first,
+// calling visit for each with the pages above it, outermost first.
+// maxDepth limits how many levels below the root are visited; 0 means no
+// limit.
+func walkPageTree(client *confluence.Client, rootID string, maxDepth int, visit func(page *confluence.Page, ancestors []*confluence.Page) error) error {
+	var walk func(pageID string, ancestors []*confluence.Page) error
+	walk = func(pageID string, ancestors []*confluence.Page) error {
+		page, err := client.GetPageByID(pageID)
+		if err != nil {
+			return fmt.Errorf("fetching page %s: %w", pageID, err)
+		}
+		if err := visit(page, ancestors); err != nil {
+			return err
+		}
+
+		if maxDepth > 0 && len(ancestors) >= maxDepth {
+			return nil
+		}
+		children, err := client.GetChildPages(page.ID)
+		if err != nil {
+			return fmt.Errorf("listing child pages of %q: %w", page.Title, err)
+		}
+		ancestors = append(ancestors[:len(ancestors):len(ancestors)], page)
+		for _, child := range children {
+			if err := walk(child.ID, ancestors); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(rootID, nil)
+}
+
+// exportPaths assigns exported pages their files. Siblings whose titles
+// map to the same file name are numbered, like siteTree.layout does, so
+// they don't overwrite each other; names are compared ignoring case for
+// case-insensitive file systems.
+type exportPaths struct {
+	// names maps page IDs to the file name chosen for the page, which
+	// also names the directory holding its children.
+	names map[string]string
+	used  map[string]bool
+}
+
+func newExportPaths() *exportPaths {
+	return &exportPaths{names: map[string]string{}, used: map[string]bool{}}
+}
+
+// file returns the path, relative to the export root, to write page to.
+// ancestors must already have been given their files.
+func (p *exportPaths) file(page *confluence.Page, ancestors []*confluence.Page) string {
+	var parts []string
+	for _, a := range ancestors {
+		parts = append(parts, p.names[a.ID])
+	}
+	dir := filepath.Join(parts...)
+
+	base := markdown.FileName(page.Title)
+	name := base
+	for i := 1; p.used[filepath.Join(dir, strings.ToLower(name))]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	p.used[filepath.Join(dir, strings.ToLower(name))] = true
+	p.names[page.ID] = name
+	return filepath.Join(dir, name+".md")
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&exportDir, "dir", "d", ".", "Directory to write the Markdown files to")
+	exportCmd.Flags().BoolVar(&exportVault, "vault", false, "Write an Obsidian vault: wikilinks, attachments/ embeds and labels as tags")
+	exportCmd.Flags().IntVar(&exportDepth, "depth", 0, "How many levels of child pages to export (0 for all)")
+	exportCmd.Flags().StringVarP(&outputFile, "output", "o", "", "With --chunk, write the NDJSON to file instead of stdout")
+	exportCmd.Flags().BoolVar(&includeMetadata, "include-metadata", false, "Include page metadata in output")
+	exportCmd.Flags().BoolVar(&frontMatter, "front-matter", false, "Prepend YAML front matter (id, version, ...) for use with push")
+	exportCmd.Flags().BoolVar(&expandIncludes, "expand-includes", false, "Expand include and excerpt-include macros with the referenced page content")
+	exportCmd.Flags().IntVar(&includeDepth, "include-depth", 3, "Maximum nesting depth when expanding includes")
+	addChunkFlags(exportCmd)
+	addConversionFlags(exportCmd)
+}
@@ -3,16 +3,23 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/justinabrahms/confluence-md/internal/confluence"
-	"github.com/justinabrahms/confluence-md/internal/config"
 	"github.com/justinabrahms/confluence-md/internal/markdown"
 )
 
 var (
 	outputFile      string
 	includeMetadata bool
+	pageVersion     int
+	frontMatter     bool
+	expandIncludes  bool
+	includeDepth    int
+	attachmentsDir  string
+	section         string
+	listSections    bool
 )
 
 var fetchCmd = &cobra.Command{
@@ -24,43 +31,86 @@ var fetchCmd = &cobra.Command{
 		pageURL := args[0]
 
 		// Load configuration
-		cfg, err := config.Load()
+		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("loading configuration: %w", err)
 		}
 
 		// Create client
-		client := confluence.NewClient(cfg.ConfluenceURL, cfg.Email, cfg.APIToken, Debug)
+		client := newClient(cfg)
+		client.ADF = wantsADF()
+		client.View = wantsView()
+
+		var opts []markdown.Option
+		if expandIncludes {
+			opts = append(opts, markdown.WithIncludes(client, includeDepth))
+		}
+		if attachmentsDir != "" {
+			downloader, err := newAttachmentDownloader(client, attachmentsDir, outputFile)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, markdown.WithAttachments(downloader))
+		}
+		converter, err := newConverter(cfg, client, opts...)
+		if err != nil {
+			return err
+		}
 
 		if Debug {
 			fmt.Fprintf(os.Stderr, "[DEBUG] Config: URL=%s, Email=%s\n", cfg.ConfluenceURL, cfg.Email)
 			fmt.Fprintf(os.Stderr, "[DEBUG] Fetching URL: %s\n", pageURL)
 		}
 
-		// Fetch page
-		page, err := client.GetPageByURL(pageURL)
+		// Fetch page, optionally at a historical version
+		var page *confluence.Page
+		if pageVersion > 0 {
+			pageID, idErr := confluence.PageIDFromURL(pageURL)
+			if idErr != nil {
+				return idErr
+			}
+			page, err = client.GetPageVersion(pageID, pageVersion)
+		} else {
+			page, err = client.GetPageByURL(pageURL)
+		}
 		if err != nil {
 			return fmt.Errorf("fetching page: %w", err)
 		}
 
 		// Convert to markdown
-		converter := markdown.NewConverter()
 		md, err := converter.PageToMarkdown(page, includeMetadata)
 		if err != nil {
 			return fmt.Errorf("converting to markdown: %w", err)
 		}
 
-		// Output
-		if outputFile != "" {
-			if err := os.WriteFile(outputFile, []byte(md), 0644); err != nil {
-				return fmt.Errorf("writing to file: %w", err)
+		if listSections {
+			return writeOutput(markdown.SectionOutline(md))
+		}
+		if section != "" {
+			md, err = markdown.ExtractSection(md, section)
+			if err != nil {
+				return err
 			}
-			fmt.Fprintf(os.Stderr, "Written to %s\n", outputFile)
-		} else {
-			fmt.Print(md)
 		}
 
-		return nil
+		if chunkMode {
+			var out strings.Builder
+			if err := writeChunks(&out, page, md, cfg.ConfluenceURL); err != nil {
+				return err
+			}
+			return writeOutput(out.String())
+		}
+
+		if frontMatter {
+			header, err := converter.Flavor().NewFrontMatter(page, cfg.ConfluenceURL).Render()
+			if err != nil {
+				return err
+			}
+			md = header + md
+		}
+
+		// Output
+		return writeOutput(md)
 	},
 }
 
@@ -68,4 +118,13 @@ func init() {
 	rootCmd.AddCommand(fetchCmd)
 	fetchCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write output to file instead of stdout")
 	fetchCmd.Flags().BoolVar(&includeMetadata, "include-metadata", false, "Include page metadata in output")
+	fetchCmd.Flags().BoolVar(&frontMatter, "front-matter", false, "Prepend YAML front matter (id, version, ...) for use with push")
+	fetchCmd.Flags().IntVar(&pageVersion, "version", 0, "Fetch a specific historical version of the page")
+	fetchCmd.Flags().BoolVar(&expandIncludes, "expand-includes", false, "Expand include and excerpt-include macros with the referenced page content")
+	fetchCmd.Flags().IntVar(&includeDepth, "include-depth", 3, "Maximum nesting depth when expanding includes")
+	fetchCmd.Flags().StringVar(&attachmentsDir, "attachments", "", "Download attached images and diagram exports into this directory")
+	fetchCmd.Flags().StringVar(&section, "section", "", `Only output the section under this heading, or a heading path like "Setup/Install"`)
+	fetchCmd.Flags().BoolVar(&listSections, "list-sections", false, "List the page's headings instead of its content")
+	addChunkFlags(fetchCmd)
+	addConversionFlags(fetchCmd)
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [url]",
+	Short: "List the version history of a Confluence page",
+	Long: `List every version of a Confluence page with its number, author, date and message.
+
+Use "fetch --version N" to retrieve the content of a specific version.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pageURL := args[0]
+
+		// Load configuration
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("loading configuration: %w", err)
+		}
+
+		// Create client
+		client := newClient(cfg)
+
+		if Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Config: URL=%s, Email=%s\n", cfg.ConfluenceURL, cfg.Email)
+			fmt.Fprintf(os.Stderr, "[DEBUG] History for URL: %s\n", pageURL)
+		}
+
+		pageID, err := confluence.PageIDFromURL(pageURL)
+		if err != nil {
+			return err
+		}
+
+		versions, err := client.GetPageVersions(pageID)
+		if err != nil {
+			return fmt.Errorf("fetching version history: %w", err)
+		}
+
+		fmt.Printf("Found %d versions:\n\n", len(versions))
+
+		for _, v := range versions {
+			fmt.Printf("[v%d] %s by %s\n",
+				v.Number,
+				v.When.Format("2006-01-02 15:04"),
+				v.By.Name())
+			if v.Message != "" {
+				fmt.Printf("    Message: %s\n", v.Message)
+			}
+			fmt.Println()
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justinabrahms/confluence-md/internal/index"
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index [dir]",
+	Short: "Build the full-text index for search --local",
+	Long: `Index the Markdown files under a directory, such as the output of
+"export", for "search --local". The index is saved in the directory as
+` + index.FileName + `.
+
+search --local rebuilds an out-of-date index by itself; run this ahead of
+time where the directory will be read-only.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		ix, err := index.Build(dir)
+		if err != nil {
+			return fmt.Errorf("indexing %s: %w", dir, err)
+		}
+		if err := ix.Save(dir); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Indexed %d pages in %s\n", len(ix.Docs), dir)
+		return nil
+	},
+}
+
+// searchLocal answers a search from the index of indexDir, printing
+// results like a Confluence search.
+func searchLocal(query string) error {
+	if mine {
+		return fmt.Errorf("--mine needs Confluence and can't be combined with --local")
+	}
+
+	ix, rebuilt, err := index.Open(indexDir)
+	if err != nil {
+		return fmt.Errorf("opening index: %w", err)
+	}
+	if rebuilt {
+		if Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Rebuilt index of %d pages in %s\n", len(ix.Docs), indexDir)
+		}
+		if err := ix.Save(indexDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	q := index.ParseQuery(query)
+	if Debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Query: %s, Terms: %v, Phrases: %v, Space: %s, Limit: %d\n", query, q.Terms, q.Phrases, spaceKey, limit)
+	}
+
+	var results []index.Result
+	for _, r := range ix.Search(q) {
+		if spaceKey != "" && r.Doc.Space != spaceKey {
+			continue
+		}
+		results = append(results, r)
+		if len(results) == limit {
+			break
+		}
+	}
+
+	selected, err := selectResult(len(results))
+	if err != nil {
+		return err
+	}
+
+	// If --lucky or --index is specified, print the file
+	if selected >= 0 {
+		doc := results[selected].Doc
+		if Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Selected result: Title=%s, Path=%s\n", doc.Title, doc.Path)
+		}
+		src, err := os.ReadFile(filepath.Join(indexDir, filepath.FromSlash(doc.Path)))
+		if err != nil {
+			return fmt.Errorf("reading result: %w", err)
+		}
+		if !includeMetadata {
+			if _, body, err := markdown.SplitFrontMatter(src); err == nil {
+				src = body
+			}
+		}
+		return writeOutput(string(src))
+	}
+
+	hits := make([]searchHit, len(results))
+	for i, r := range results {
+		hits[i] = searchHit{
+			title:   r.Doc.Title,
+			space:   r.Doc.Space,
+			updated: r.Doc.Updated,
+			url:     r.Doc.URL,
+			file:    filepath.Join(indexDir, filepath.FromSlash(r.Doc.Path)),
+			snippet: q.Snippet(r.Doc),
+		}
+	}
+	printSearchResults(hits)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// writeOutput writes content to the --output file when one is given,
+// otherwise to stdout.
+func writeOutput(content string) error {
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Written to %s\n", outputFile)
+		return nil
+	}
+	fmt.Print(content)
+	return nil
+}
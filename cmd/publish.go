@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	publishSpace  string
+	publishParent string
+	publishTitle  string
+)
+
+var publishCmd = &cobra.Command{
+	Use:   "publish [file]",
+	Short: "Publish a Markdown file as a new Confluence page",
+	Long: `Convert a local Markdown file to Confluence storage format and create it as
+a new page.
+
+The page title is taken from --title, or from a level-one heading at the top
+of the file. Headings, lists, tables, code fences, task lists and GFM alerts
+("> [!NOTE]") are converted to their Confluence equivalents.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath := args[0]
+
+		src, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("reading file: %w", err)
+		}
+
+		title, storage, err := markdown.ToStorage(src)
+		if err != nil {
+			return fmt.Errorf("converting to storage format: %w", err)
+		}
+		if publishTitle != "" {
+			title = publishTitle
+		}
+		if title == "" {
+			return fmt.Errorf("no page title: pass --title or start the file with a level-one heading")
+		}
+
+		parentID := publishParent
+		if strings.Contains(parentID, "/pages/") {
+			parentID, err = confluence.PageIDFromURL(parentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Load configuration
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("loading configuration: %w", err)
+		}
+
+		// Create client
+		client := newClient(cfg)
+
+		if Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Config: URL=%s, Email=%s\n", cfg.ConfluenceURL, cfg.Email)
+			fmt.Fprintf(os.Stderr, "[DEBUG] Publishing %s as %q to space %s (parent: %s)\n", filePath, title, publishSpace, parentID)
+		}
+
+		page, err := client.CreatePage(publishSpace, parentID, title, storage)
+		if err != nil {
+			return fmt.Errorf("creating page: %w", err)
+		}
+
+		fmt.Printf("Created page %q (ID: %s)\n", page.Title, page.ID)
+		fmt.Printf("URL: %s\n", cfg.ConfluenceURL+page.Links.WebUI)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+	publishCmd.Flags().StringVar(&publishSpace, "space", "", "Space key to create the page in")
+	publishCmd.Flags().StringVar(&publishParent, "parent", "", "Parent page ID or URL")
+	publishCmd.Flags().StringVar(&publishTitle, "title", "", "Page title (default: the file's leading H1)")
+	publishCmd.MarkFlagRequired("space")
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pushForce   bool
+	pushMessage string
+)
+
+var pushCmd = &cobra.Command{
+	Use:   "push [file]",
+	Short: "Update a Confluence page from a Markdown file",
+	Long: `Update the Confluence page identified by a Markdown file's front matter.
+
+The file must start with the front matter written by "fetch --front-matter".
+The push is refused if the page has been edited since the version recorded in
+the file, unless --force is given. On success the file's recorded version is
+updated to the new page version.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath := args[0]
+
+		src, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("reading file: %w", err)
+		}
+
+		fm, body, err := markdown.SplitFrontMatter(src)
+		if err != nil {
+			return err
+		}
+		if fm == nil || fm.ID == "" {
+			return fmt.Errorf("%s has no front matter page id (fetch it with --front-matter first)", filePath)
+		}
+
+		title, storage, err := markdown.ToStorage(body)
+		if err != nil {
+			return fmt.Errorf("converting to storage format: %w", err)
+		}
+		if title == "" {
+			title = fm.Title
+		}
+
+		// Load configuration
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("loading configuration: %w", err)
+		}
+
+		// Create client
+		client := newClient(cfg)
+
+		if Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Config: URL=%s, Email=%s\n", cfg.ConfluenceURL, cfg.Email)
+			fmt.Fprintf(os.Stderr, "[DEBUG] Pushing %s to page %s (local version %d)\n", filePath, fm.ID, fm.Version)
+		}
+
+		remote, err := client.GetPageByID(fm.ID)
+		if err != nil {
+			return fmt.Errorf("fetching page: %w", err)
+		}
+
+		if remote.Version.Number > fm.Version && !pushForce {
+			return fmt.Errorf("page has been edited since it was fetched (remote v%d, local v%d); re-fetch it or use --force to overwrite",
+				remote.Version.Number, fm.Version)
+		}
+
+		page, err := client.UpdatePage(fm.ID, title, storage, remote.Version.Number+1, pushMessage)
+		if err != nil {
+			return fmt.Errorf("updating page: %w", err)
+		}
+
+		// Record the new version so the next push is checked against it
+		fm.Title = page.Title
+		fm.Version = page.Version.Number
+		header, err := fm.Render()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, append([]byte(header), body...), 0644); err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+
+		fmt.Printf("Updated page %q to version %d\n", page.Title, page.Version.Number)
+		if page.Links.WebUI != "" {
+			fmt.Printf("URL: %s\n", cfg.ConfluenceURL+page.Links.WebUI)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+	pushCmd.Flags().BoolVar(&pushForce, "force", false, "Overwrite the page even if it changed since it was fetched")
+	pushCmd.Flags().StringVarP(&pushMessage, "message", "m", "", "Version comment for the update")
+}
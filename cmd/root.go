@@ -9,6 +9,8 @@ import (
 
 var (
 	Debug   bool
+	NoCache bool
+	Offline bool
 	Version string = "dev" // Set via ldflags during build
 )
 
@@ -19,10 +21,18 @@ var rootCmd = &cobra.Command{
 
 Fetch pages by URL or search for pages by name and retrieve their content.`,
 	Version: Version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if Offline && NoCache {
+			return fmt.Errorf("--offline serves pages from the cache and can't be combined with --no-cache")
+		}
+		return nil
+	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&Debug, "debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().BoolVar(&NoCache, "no-cache", false, "Don't read or write the response cache")
+	rootCmd.PersistentFlags().BoolVar(&Offline, "offline", false, "Serve requests from the response cache only, without contacting Confluence")
 }
 
 func Execute() {
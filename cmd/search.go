@@ -3,11 +3,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/justinabrahms/confluence-md/internal/confluence"
-	"github.com/justinabrahms/confluence-md/internal/config"
-	"github.com/justinabrahms/confluence-md/internal/markdown"
 )
 
 var (
@@ -16,24 +14,42 @@ var (
 	lucky       bool
 	resultIndex int
 	mine        bool
+	localSearch bool
+	indexDir    string
 )
 
 var searchCmd = &cobra.Command{
 	Use:   "search [query]",
 	Short: "Search for Confluence pages",
-	Long:  `Search for Confluence pages by query string and optionally fetch the content.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Search for Confluence pages by query string and optionally fetch the content.
+
+With --local, search the Markdown files under --dir instead, such as the
+output of "export", without contacting Confluence or needing credentials.
+Local queries are ranked with BM25, match words by their stem, and treat
+"quoted phrases" as required.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query := args[0]
 
+		if localSearch {
+			return searchLocal(query)
+		}
+
 		// Load configuration
-		cfg, err := config.Load()
+		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("loading configuration: %w", err)
 		}
 
 		// Create client
-		client := confluence.NewClient(cfg.ConfluenceURL, cfg.Email, cfg.APIToken, Debug)
+		client := newClient(cfg)
+		client.ADF = wantsADF()
+		client.View = wantsView()
+
+		converter, err := newConverter(cfg, client)
+		if err != nil {
+			return err
+		}
 
 		if Debug {
 			fmt.Fprintf(os.Stderr, "[DEBUG] Config: URL=%s, Email=%s\n", cfg.ConfluenceURL, cfg.Email)
@@ -46,23 +62,14 @@ var searchCmd = &cobra.Command{
 			return fmt.Errorf("searching: %w", err)
 		}
 
-		if results.Size == 0 {
-			fmt.Println("No results found")
-			os.Exit(4)
+		selected, err := selectResult(len(results.Results))
+		if err != nil {
+			return err
 		}
 
 		// If --lucky or --index is specified, fetch the content
-		if lucky || resultIndex > 0 {
-			fetchIndex := 0
-			if resultIndex > 0 {
-				fetchIndex = resultIndex - 1 // Convert to 0-based
-			}
-
-			if fetchIndex >= len(results.Results) {
-				return fmt.Errorf("index %d out of range (found %d results)", resultIndex, len(results.Results))
-			}
-
-			result := results.Results[fetchIndex]
+		if selected >= 0 {
+			result := results.Results[selected]
 			if Debug {
 				fmt.Fprintf(os.Stderr, "[DEBUG] Selected result: Title=%s, ID=%s, Type=%s\n",
 					result.Title, result.ID, result.Type)
@@ -72,41 +79,87 @@ var searchCmd = &cobra.Command{
 				return fmt.Errorf("fetching page: %w", err)
 			}
 
-			converter := markdown.NewConverter()
 			md, err := converter.PageToMarkdown(page, includeMetadata)
 			if err != nil {
 				return fmt.Errorf("converting to markdown: %w", err)
 			}
 
-			if outputFile != "" {
-				if err := os.WriteFile(outputFile, []byte(md), 0644); err != nil {
-					return fmt.Errorf("writing to file: %w", err)
-				}
-				fmt.Fprintf(os.Stderr, "Written to %s\n", outputFile)
-			} else {
-				fmt.Print(md)
-			}
-
-			return nil
+			return writeOutput(md)
 		}
 
-		// Display search results
-		fmt.Printf("Found %d results:\n\n", results.Size)
-
+		hits := make([]searchHit, len(results.Results))
 		for i, result := range results.Results {
-			pageURL := cfg.ConfluenceURL + result.Links.WebUI
-
-			fmt.Printf("[%d] %s\n", i+1, result.Title)
-			fmt.Printf("    Space: %s | Updated: %s\n",
-				result.Space.Key,
-				result.LastModified.Format("2006-01-02"))
-			fmt.Printf("    URL: %s\n\n", pageURL)
+			hits[i] = searchHit{
+				title:   result.Title,
+				space:   result.Space.Key,
+				updated: result.LastModified,
+				url:     cfg.ConfluenceURL + result.Links.WebUI,
+			}
 		}
-
+		printSearchResults(hits)
 		return nil
 	},
 }
 
+// searchHit is a search result from Confluence or the local index, as
+// listed to the user.
+type searchHit struct {
+	title   string
+	space   string
+	updated time.Time
+	url     string
+	// file and snippet are only known for local results.
+	file    string
+	snippet string
+}
+
+// selectResult returns the 0-based index of the result --lucky or --index
+// asks for among count results, or -1 when the results should be listed.
+// It exits when there are no results.
+func selectResult(count int) (int, error) {
+	if count == 0 {
+		fmt.Println("No results found")
+		os.Exit(4)
+	}
+	if !lucky && resultIndex <= 0 {
+		return -1, nil
+	}
+
+	fetchIndex := 0
+	if resultIndex > 0 {
+		fetchIndex = resultIndex - 1 // Convert to 0-based
+	}
+	if fetchIndex >= count {
+		return 0, fmt.Errorf("index %d out of range (found %d results)", resultIndex, count)
+	}
+	return fetchIndex, nil
+}
+
+// printSearchResults lists search results with their 1-based index for
+// --index.
+func printSearchResults(hits []searchHit) {
+	fmt.Printf("Found %d results:\n\n", len(hits))
+
+	for i, hit := range hits {
+		fmt.Printf("[%d] %s\n", i+1, hit.title)
+		if hit.space != "" {
+			fmt.Printf("    Space: %s | Updated: %s\n", hit.space, hit.updated.Format("2006-01-02"))
+		} else {
+			fmt.Printf("    Updated: %s\n", hit.updated.Format("2006-01-02"))
+		}
+		if hit.url != "" {
+			fmt.Printf("    URL: %s\n", hit.url)
+		}
+		if hit.file != "" {
+			fmt.Printf("    File: %s\n", hit.file)
+		}
+		if hit.snippet != "" {
+			fmt.Printf("    %s\n", hit.snippet)
+		}
+		fmt.Println()
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(searchCmd)
 	searchCmd.Flags().StringVar(&spaceKey, "space", "", "Limit search to specific space")
@@ -116,4 +169,7 @@ func init() {
 	searchCmd.Flags().BoolVar(&mine, "mine", false, "Only search pages you created")
 	searchCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write output to file instead of stdout")
 	searchCmd.Flags().BoolVar(&includeMetadata, "include-metadata", false, "Include page metadata in output")
+	searchCmd.Flags().BoolVar(&localSearch, "local", false, "Search exported Markdown files instead of Confluence")
+	searchCmd.Flags().StringVar(&indexDir, "dir", ".", "Directory of Markdown files to search with --local")
+	addConversionFlags(searchCmd)
 }
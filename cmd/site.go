@@ -0,0 +1,398 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	siteGenerator string
+	siteDir       string
+)
+
+var siteCmd = &cobra.Command{
+	Use:   "site [space-key|url]",
+	Short: "Export a space as a MkDocs or Hugo site",
+	Long: `Export every page of a Confluence space, or a page and its descendants,
+as the content of a static site.
+
+With --generator mkdocs, pages are written to docs/ and the nav in
+mkdocs.yml is built from the page tree. With --generator hugo, pages are
+written to content/ as sections with _index.md files, weighted in
+Confluence's page order. Links between exported pages point at their files
+and attachments are downloaded alongside. A lone top-level page, such as a
+space's home page, becomes the site's home page.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source := args[0]
+		if siteGenerator != "mkdocs" && siteGenerator != "hugo" {
+			return fmt.Errorf("unknown site generator %q (expected mkdocs or hugo)", siteGenerator)
+		}
+
+		// Load configuration
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("loading configuration: %w", err)
+		}
+
+		// Create client
+		client := newClient(cfg)
+		client.ADF = wantsADF()
+		client.View = wantsView()
+
+		if Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Config: URL=%s, Email=%s\n", cfg.ConfluenceURL, cfg.Email)
+			fmt.Fprintf(os.Stderr, "[DEBUG] Building %s site from %s\n", siteGenerator, source)
+		}
+
+		var roots []confluence.Page
+		if strings.Contains(source, "/") {
+			pageID, err := confluence.PageIDFromURL(source)
+			if err != nil {
+				return err
+			}
+			roots = []confluence.Page{{ID: pageID}}
+		} else {
+			roots, err = client.GetSpaceRootPages(source)
+			if err != nil {
+				return fmt.Errorf("listing pages of space %s: %w", source, err)
+			}
+		}
+
+		site := newSiteTree(siteGenerator)
+		for _, root := range roots {
+			if err := walkPageTree(client, root.ID, 0, site.add); err != nil {
+				return err
+			}
+		}
+		if len(site.roots) == 0 {
+			return fmt.Errorf("no pages found in %s", source)
+		}
+		site.layout()
+
+		contentDir := filepath.Join(siteDir, "docs")
+		attachmentsDir := filepath.Join(contentDir, "attachments")
+		if siteGenerator == "hugo" {
+			contentDir = filepath.Join(siteDir, "content")
+			attachmentsDir = filepath.Join(siteDir, "static", "attachments")
+		}
+		downloader, err := newAttachmentDownloader(client, attachmentsDir, "")
+		if err != nil {
+			return err
+		}
+
+		flavorName = siteGenerator
+		converter, err := newConverter(cfg, client,
+			markdown.WithPageLinks(site),
+			markdown.WithAttachments(&siteAttachments{downloader: downloader, site: site}))
+		if err != nil {
+			return err
+		}
+
+		for _, node := range site.nodes() {
+			md, err := converter.PageToMarkdown(node.page, false)
+			if err != nil {
+				return fmt.Errorf("converting page %q: %w", node.page.Title, err)
+			}
+			fm := converter.Flavor().NewFrontMatter(node.page, cfg.ConfluenceURL)
+			if siteGenerator == "hugo" {
+				fm.Weight = node.weight
+			}
+			header, err := fm.Render()
+			if err != nil {
+				return err
+			}
+
+			target := filepath.Join(contentDir, filepath.FromSlash(node.file))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("creating directory: %w", err)
+			}
+			if err := os.WriteFile(target, []byte(header+md+"\n"), 0644); err != nil {
+				return fmt.Errorf("writing %s: %w", target, err)
+			}
+			if Debug {
+				fmt.Fprintf(os.Stderr, "[DEBUG] Wrote %s\n", target)
+			}
+		}
+
+		if siteGenerator == "hugo" {
+			err = writeHugoConfig(siteDir, site)
+		} else {
+			err = writeMkDocsConfig(siteDir, site)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Exported %d pages to %s\n", len(site.nodes()), siteDir)
+		return nil
+	},
+}
+
+// siteNode is a page in the exported site.
+type siteNode struct {
+	page     *confluence.Page
+	children []*siteNode
+	// weight is the page's 1-based position among its siblings.
+	weight int
+	// file is the page's path below the content directory, with slashes.
+	file string
+}
+
+// siteTree is the page tree of a site being exported. It implements
+// markdown.PageLinker for links between exported pages.
+type siteTree struct {
+	generator string
+	roots     []*siteNode
+	byID      map[string]*siteNode
+	byTitle   map[string]*siteNode
+}
+
+func newSiteTree(generator string) *siteTree {
+	return &siteTree{generator: generator, byID: map[string]*siteNode{}, byTitle: map[string]*siteNode{}}
+}
+
+// add records a page visited by walkPageTree.
+func (s *siteTree) add(page *confluence.Page, ancestors []*confluence.Page) error {
+	node := &siteNode{page: page}
+	if len(ancestors) == 0 {
+		s.roots = append(s.roots, node)
+		node.weight = len(s.roots)
+	} else {
+		parent := s.byID[ancestors[len(ancestors)-1].ID]
+		parent.children = append(parent.children, node)
+		node.weight = len(parent.children)
+	}
+	s.byID[page.ID] = node
+	s.byTitle[page.Space.Key+"\x00"+page.Title] = node
+	return nil
+}
+
+// layout assigns each page its file. Pages with children become a
+// directory with an index file; a lone root page becomes the site's home.
+func (s *siteTree) layout() {
+	index := "index.md"
+	if s.generator == "hugo" {
+		index = "_index.md"
+	}
+
+	var place func(nodes []*siteNode, dir string)
+	place = func(nodes []*siteNode, dir string) {
+		used := map[string]int{}
+		for _, node := range nodes {
+			slug := markdown.Slug(node.page.Title)
+			if n := used[slug]; n > 0 {
+				used[slug] = n + 1
+				slug = fmt.Sprintf("%s-%d", slug, n)
+			} else {
+				used[slug] = 1
+			}
+
+			if len(node.children) == 0 {
+				node.file = path.Join(dir, slug+".md")
+				continue
+			}
+			node.file = path.Join(dir, slug, index)
+			place(node.children, path.Join(dir, slug))
+		}
+	}
+
+	if len(s.roots) == 1 {
+		s.roots[0].file = index
+		place(s.roots[0].children, "")
+		return
+	}
+	place(s.roots, "")
+}
+
+// nodes returns every page in the site, depth first.
+func (s *siteTree) nodes() []*siteNode {
+	var all []*siteNode
+	var walk func(nodes []*siteNode)
+	walk = func(nodes []*siteNode) {
+		for _, node := range nodes {
+			all = append(all, node)
+			walk(node.children)
+		}
+	}
+	walk(s.roots)
+	return all
+}
+
+// PageLink implements markdown.PageLinker. MkDocs resolves links relative
+// to the linking file; Hugo links go through its relref shortcode.
+func (s *siteTree) PageLink(from *confluence.Page, spaceKey, title, anchor string) (string, bool) {
+	target, ok := s.byTitle[spaceKey+"\x00"+title]
+	if !ok {
+		return "", false
+	}
+	if anchor != "" {
+		anchor = "#" + anchor
+	}
+	if s.generator == "hugo" {
+		return `{{< relref "/` + target.file + anchor + `" >}}`, true
+	}
+	return s.relative(from, target.file) + anchor, true
+}
+
+// relative returns the path to file, below the content directory, as
+// seen from the file of page from.
+func (s *siteTree) relative(from *confluence.Page, file string) string {
+	dir := ""
+	if node, ok := s.byID[from.ID]; ok {
+		dir = path.Dir(node.file)
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(file))
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+// siteAttachments links to attachments downloaded for a site. MkDocs
+// links are relative to the page's file; Hugo serves static/attachments
+// at /attachments/, which is linked to relative to the page's URL.
+type siteAttachments struct {
+	downloader *attachmentDownloader
+	site       *siteTree
+}
+
+func (a *siteAttachments) Attachment(page *confluence.Page, filename string) (string, error) {
+	link, err := a.downloader.Attachment(page, filename)
+	if err != nil {
+		return "", err
+	}
+	name := path.Base(link)
+
+	if a.site.generator != "hugo" {
+		return a.site.relative(page, path.Join("attachments", name)), nil
+	}
+	depth := 0
+	if node, ok := a.site.byID[page.ID]; ok {
+		url := strings.TrimSuffix(strings.TrimSuffix(node.file, ".md"), "_index")
+		if url = strings.Trim(url, "/"); url != "" {
+			depth = strings.Count(url, "/") + 1
+		}
+	}
+	return strings.Repeat("../", depth) + "attachments/" + name, nil
+}
+
+func (a *siteAttachments) HasAttachment(page *confluence.Page, filename string) bool {
+	return a.downloader.HasAttachment(page, filename)
+}
+
+// writeMkDocsConfig writes the site's nav to mkdocs.yml, keeping the rest
+// of an existing file.
+func writeMkDocsConfig(dir string, site *siteTree) error {
+	var nav func(nodes []*siteNode) []interface{}
+	nav = func(nodes []*siteNode) []interface{} {
+		var items []interface{}
+		for _, node := range nodes {
+			if len(node.children) == 0 {
+				items = append(items, map[string]interface{}{node.page.Title: node.file})
+				continue
+			}
+			section := append([]interface{}{node.file}, nav(node.children)...)
+			items = append(items, map[string]interface{}{node.page.Title: section})
+		}
+		return items
+	}
+
+	var items []interface{}
+	if len(site.roots) == 1 {
+		items = append([]interface{}{map[string]interface{}{"Home": "index.md"}}, nav(site.roots[0].children)...)
+	} else {
+		items = nav(site.roots)
+	}
+	var navNode yaml.Node
+	if err := navNode.Encode(items); err != nil {
+		return fmt.Errorf("encoding nav: %w", err)
+	}
+
+	target := filepath.Join(dir, "mkdocs.yml")
+	var doc yaml.Node
+	if data, err := os.ReadFile(target); err == nil {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parsing %s: %w", target, err)
+		}
+	}
+	if len(doc.Content) == 0 {
+		fresh := struct {
+			SiteName           string   `yaml:"site_name"`
+			MarkdownExtensions []string `yaml:"markdown_extensions"`
+		}{siteTitle(site), []string{"admonition", "tables", "toc"}}
+		var mapping yaml.Node
+		if err := mapping.Encode(fresh); err != nil {
+			return fmt.Errorf("encoding %s: %w", target, err)
+		}
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&mapping}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a YAML mapping", target)
+	}
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "nav" {
+			root.Content[i+1] = &navNode
+			replaced = true
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "nav"}, &navNode)
+	}
+
+	data, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", target, err)
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", target, err)
+	}
+	return nil
+}
+
+// siteTitle names the site after its space, or its first page when the
+// space name isn't known.
+func siteTitle(site *siteTree) string {
+	if name := site.roots[0].page.Space.Name; name != "" {
+		return name
+	}
+	return site.roots[0].page.Title
+}
+
+// writeHugoConfig writes a minimal hugo.toml unless the site already has a
+// configuration file. Raw HTML must be allowed for tables and <details>
+// blocks to render.
+func writeHugoConfig(dir string, site *siteTree) error {
+	for _, name := range []string{"hugo.toml", "hugo.yaml", "hugo.json", "config.toml", "config.yaml", "config.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return nil
+		}
+	}
+
+	config := fmt.Sprintf("title = %q\n\n[markup.goldmark.renderer]\n  unsafe = true\n", siteTitle(site))
+	target := filepath.Join(dir, "hugo.toml")
+	if err := os.WriteFile(target, []byte(config), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", target, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(siteCmd)
+	siteCmd.Flags().StringVarP(&siteGenerator, "generator", "g", "mkdocs", "Static site generator to write for: mkdocs or hugo")
+	siteCmd.Flags().StringVarP(&siteDir, "dir", "d", ".", "Site root directory")
+	addConversionFlags(siteCmd)
+	// The generator decides the flavor
+	siteCmd.Flags().MarkHidden("flavor")
+}
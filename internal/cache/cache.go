@@ -0,0 +1,204 @@
+// Package cache stores Confluence API responses on disk, so pages fetched
+// again can be revalidated or reused instead of downloaded in full.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a cached response to a GET request.
+type Entry struct {
+	URL string `json:"url"`
+	// Profile identifies the credentials the response was fetched with,
+	// since what a request returns depends on who makes it.
+	Profile      string `json:"profile"`
+	Status       int    `json:"status"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Version is the number of the page version the response holds, or 0
+	// when it isn't a page.
+	Version int       `json:"version,omitempty"`
+	Stored  time.Time `json:"stored"`
+	Body    []byte    `json:"body"`
+
+	// Used is when the entry was last stored or served.
+	Used time.Time `json:"-"`
+	// Size is the size of the entry on disk.
+	Size int64 `json:"-"`
+}
+
+// Cache is a directory of cached responses, one file per URL and profile.
+type Cache struct {
+	Dir string
+}
+
+// DefaultDir returns $XDG_CACHE_HOME/confluence-md, or
+// ~/.cache/confluence-md when XDG_CACHE_HOME isn't set.
+func DefaultDir() string {
+	cacheDir := os.Getenv("XDG_CACHE_HOME")
+	if cacheDir == "" {
+		home, _ := os.UserHomeDir()
+		cacheDir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheDir, "confluence-md")
+}
+
+// Open returns the cache in dir, creating the directory if needed.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+func (c *Cache) path(profile, url string) string {
+	sum := sha256.Sum256([]byte(profile + "\x00" + url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the cached response to url for profile, or nil if there is
+// none.
+func (c *Cache) Get(profile, url string) *Entry {
+	e, err := readEntry(c.path(profile, url))
+	if err != nil || e.URL != url || e.Profile != profile {
+		return nil
+	}
+	return e
+}
+
+// Put stores e, replacing any earlier response to the same URL.
+func (c *Cache) Put(e *Entry) error {
+	if e.Stored.IsZero() {
+		e.Stored = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding cache entry: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see half an entry
+	target := c.path(e.Profile, e.URL)
+	tmp, err := os.CreateTemp(c.Dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	return nil
+}
+
+// Touch records that e was served, which keeps it from being pruned.
+func (c *Cache) Touch(e *Entry) {
+	now := time.Now()
+	os.Chtimes(c.path(e.Profile, e.URL), now, now)
+}
+
+// Entries returns every entry in the cache, sorted by URL.
+func (c *Cache) Entries() ([]*Entry, error) {
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, file := range files {
+		e, err := readEntry(file)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+		return entries[i].Profile < entries[j].Profile
+	})
+	return entries, nil
+}
+
+// Prune removes entries last used before cutoff, and files that aren't
+// readable entries. It returns the number of files removed.
+func (c *Cache) Prune(cutoff time.Time) (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, file := range files {
+		if e, err := readEntry(file); err == nil && !e.Used.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return removed, fmt.Errorf("removing cache entry: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Clear removes every entry and returns the number removed.
+func (c *Cache) Clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	for i, file := range files {
+		if err := os.Remove(file); err != nil {
+			return i, fmt.Errorf("removing cache entry: %w", err)
+		}
+	}
+	return len(files), nil
+}
+
+func (c *Cache) files() ([]string, error) {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cache directory: %w", err)
+	}
+	var files []string
+	for _, d := range dirEntries {
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".json") {
+			files = append(files, filepath.Join(c.Dir, d.Name()))
+		}
+	}
+	return files, nil
+}
+
+func readEntry(file string) (*Entry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("parsing cache entry %s: %w", file, err)
+	}
+	e.Used = info.ModTime()
+	e.Size = info.Size()
+	return &e, nil
+}
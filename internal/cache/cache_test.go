@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCache_PutGet(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	url := "https://example.com/rest/api/content/1"
+	if e := c.Get("a@example.com", url); e != nil {
+		t.Fatalf("expected no entry, got %+v", e)
+	}
+
+	err = c.Put(&Entry{URL: url, Profile: "a@example.com", Status: 200, ETag: `"v1"`, Version: 3, Body: []byte(`{"id":"1"}`)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := c.Get("a@example.com", url)
+	if e == nil {
+		t.Fatal("expected an entry")
+	}
+	if e.ETag != `"v1"` || e.Version != 3 || string(e.Body) != `{"id":"1"}` || e.Stored.IsZero() {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e := c.Get("b@example.com", url); e != nil {
+		t.Errorf("expected entries to be kept per profile, got %+v", e)
+	}
+}
+
+func TestCache_PruneAndClear(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, url := range []string{"https://example.com/old", "https://example.com/new"} {
+		if err := c.Put(&Entry{URL: url, Profile: "a", Status: 200}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(c.path("a", "https://example.com/old"), old, old); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	removed, err := c.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 entry pruned, got %d, %v", removed, err)
+	}
+	entries, err := c.Entries()
+	if err != nil || len(entries) != 1 || entries[0].URL != "https://example.com/new" {
+		t.Fatalf("unexpected entries after prune: %+v, %v", entries, err)
+	}
+
+	c.Touch(entries[0])
+	if removed, err := c.Clear(); err != nil || removed != 1 {
+		t.Fatalf("expected 1 entry cleared, got %d, %v", removed, err)
+	}
+	if entries, _ := c.Entries(); len(entries) != 0 {
+		t.Errorf("expected empty cache, got %+v", entries)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/justinabrahms/confluence-md/internal/confluence"
 	"gopkg.in/yaml.v3"
 )
 
@@ -12,9 +14,23 @@ type Config struct {
 	ConfluenceURL   string `yaml:"confluence_url"`
 	Email           string `yaml:"email"`
 	APIToken        string `yaml:"api_token"`
+	APIVersion      string `yaml:"api_version"`
+	JiraURL         string `yaml:"jira_url"`
 }
 
+// Load reads the configuration file and environment, and checks that
+// everything needed to talk to Confluence is set.
 func Load() (*Config, error) {
+	return load(true)
+}
+
+// LoadOffline is like Load for runs served from the response cache, which
+// need no API token: cached responses are found by URL and email alone.
+func LoadOffline() (*Config, error) {
+	return load(false)
+}
+
+func load(needToken bool) (*Config, error) {
 	cfg := &Config{}
 
 	// Try to load from XDG config file first
@@ -40,6 +56,12 @@ func Load() (*Config, error) {
 	if token := os.Getenv("CONFLUENCE_API_TOKEN"); token != "" {
 		cfg.APIToken = token
 	}
+	if apiVersion := os.Getenv("CONFLUENCE_API_VERSION"); apiVersion != "" {
+		cfg.APIVersion = apiVersion
+	}
+	if jiraURL := os.Getenv("JIRA_URL"); jiraURL != "" {
+		cfg.JiraURL = jiraURL
+	}
 
 	// Validate required fields
 	if cfg.ConfluenceURL == "" {
@@ -48,10 +70,21 @@ func Load() (*Config, error) {
 	if cfg.Email == "" {
 		return nil, fmt.Errorf("email not set (check config file or CONFLUENCE_EMAIL env var)")
 	}
-	if cfg.APIToken == "" {
+	if needToken && cfg.APIToken == "" {
 		return nil, fmt.Errorf("api_token not set (check config file or CONFLUENCE_API_TOKEN env var)")
 	}
 
+	apiVersion, err := confluence.ParseAPIVersion(cfg.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("api_version: %w", err)
+	}
+	cfg.APIVersion = apiVersion
+
+	// Cloud sites serve Jira from the root of the Confluence site
+	if cfg.JiraURL == "" && strings.HasSuffix(strings.TrimSuffix(cfg.ConfluenceURL, "/"), "/wiki") {
+		cfg.JiraURL = strings.TrimSuffix(strings.TrimSuffix(cfg.ConfluenceURL, "/"), "/wiki")
+	}
+
 	return cfg, nil
 }
 
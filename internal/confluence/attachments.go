@@ -0,0 +1,69 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Attachment is a file attached to a page.
+type Attachment struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	Version    Version `json:"version"`
+	Extensions struct {
+		FileSize int64 `json:"fileSize"`
+	} `json:"extensions"`
+	Links Links `json:"_links"`
+}
+
+type attachmentList struct {
+	Results []Attachment `json:"results"`
+}
+
+// FindAttachment looks up the named attachment of a page.
+func (c *Client) FindAttachment(pageID, filename string) (*Attachment, error) {
+	c.debugf("Looking up attachment %q of page %s", filename, pageID)
+
+	path := fmt.Sprintf("/rest/api/content/%s/child/attachment?filename=%s&expand=version", pageID, url.QueryEscape(filename))
+	resp, err := c.doRequest("GET", path)
+	if err != nil {
+		return nil, err
+	}
+	var list attachmentList
+	err = json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("decoding attachments: %w", err)
+	}
+	if len(list.Results) == 0 || list.Results[0].Links.Download == "" {
+		return nil, fmt.Errorf("attachment %q not found on page %s", filename, pageID)
+	}
+	return &list.Results[0], nil
+}
+
+// GetAttachment downloads the named attachment of a page.
+func (c *Client) GetAttachment(pageID, filename string) ([]byte, error) {
+	a, err := c.FindAttachment(pageID, filename)
+	if err != nil {
+		return nil, err
+	}
+	return c.DownloadAttachment(a)
+}
+
+// DownloadAttachment downloads an attachment found with FindAttachment.
+func (c *Client) DownloadAttachment(a *Attachment) ([]byte, error) {
+	c.debugf("Fetching attachment %q", a.Title)
+	resp, err := c.doDownload(a.Links.Download)
+	if err != nil {
+		return nil, fmt.Errorf("downloading attachment %q: %w", a.Title, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("downloading attachment %q: %w", a.Title, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,55 @@
+package confluence
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetAttachment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wiki/rest/api/content/123/child/attachment":
+			if got := r.URL.Query().Get("filename"); got != "flow chart.png" {
+				t.Errorf("unexpected filename %q", got)
+			}
+			fmt.Fprint(w, `{"results":[{"id":"att1","title":"flow chart.png","version":{"number":3,"when":"2024-05-01T10:00:00.000Z"},"extensions":{"fileSize":7},"_links":{"download":"/download/attachments/123/flow%20chart.png?version=1"}}]}`)
+		case "/wiki/download/attachments/123/flow chart.png":
+			fmt.Fprint(w, "PNGDATA")
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/wiki", "user@example.com", "token", false)
+	a, err := client.FindAttachment("123", "flow chart.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.Version.Number != 3 || a.Extensions.FileSize != 7 {
+		t.Errorf("unexpected attachment metadata: %+v", a)
+	}
+
+	data, err := client.GetAttachment("123", "flow chart.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "PNGDATA" {
+		t.Errorf("unexpected attachment data: %q", data)
+	}
+}
+
+func TestClient_FindAttachment_Missing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results":[]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/wiki", "user@example.com", "token", false)
+	if a, err := client.FindAttachment("123", "flow.svg"); err == nil {
+		t.Errorf("expected an error for a missing attachment, got %+v", a)
+	}
+}
@@ -0,0 +1,96 @@
+package confluence
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// API versions accepted by Client.APIVersion.
+const (
+	APIAuto = "auto"
+	APIV1   = "v1"
+	APIV2   = "v2"
+)
+
+// pageBackend is implemented by each REST API version the client can talk
+// to. Search, user lookups and attachments are not part of it and always
+// use v1, which Cloud serves alongside v2: CQL search and user lookups
+// only exist in v1, and v1 finds an attachment by file name in one request.
+type pageBackend interface {
+	// getPage fetches a page, at a historical version when version > 0.
+	getPage(pageID string, version int) (*Page, error)
+	// currentVersion looks up the number of a page's current version.
+	currentVersion(pageID string) (int, error)
+	getVersions(pageID string) ([]Version, error)
+	getInlineComments(pageID string) ([]Comment, error)
+	// getChildPages lists a page's direct children in their Confluence
+	// order, with only their ID and title filled in.
+	getChildPages(pageID string) ([]Page, error)
+	getLabels(pageID string) ([]string, error)
+	// getRootPages lists the top-level pages of a space, like
+	// getChildPages.
+	getRootPages(spaceKey string) ([]Page, error)
+	// findPageID looks up a page by space key and exact title.
+	findPageID(spaceKey, title string) (string, error)
+	createPage(spaceKey, parentID, title, storage string) (*Page, error)
+	updatePage(pageID, title, storage string, version int, message string) (*Page, error)
+}
+
+// HTTPError is returned for non-2xx responses from Confluence.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// IsNotFound reports whether err is an HTTP 404 from Confluence.
+func IsNotFound(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// ParseAPIVersion validates an api_version setting.
+func ParseAPIVersion(s string) (string, error) {
+	switch s {
+	case "", APIAuto:
+		return APIAuto, nil
+	case APIV1, APIV2:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown API version %q (expected auto, v1 or v2)", s)
+}
+
+// api returns the backend for the client's APIVersion. In auto mode the
+// v2 API is probed once: sites answering it use v2, and sites without it
+// (Data Center) answer 404 and fall back to v1. Any other failure is
+// returned, and the probe is retried on the next call.
+func (c *Client) api() (pageBackend, error) {
+	if c.backend != nil {
+		return c.backend, nil
+	}
+
+	switch c.APIVersion {
+	case APIV1:
+		c.backend = &v1Backend{c: c}
+	case APIV2:
+		c.backend = newV2Backend(c)
+	default:
+		resp, err := c.doRequest("GET", "/api/v2/spaces?limit=1")
+		switch {
+		case err == nil:
+			resp.Body.Close()
+			c.debugf("Using REST API v2")
+			c.backend = newV2Backend(c)
+		case IsNotFound(err):
+			c.debugf("REST API v2 not available, falling back to v1")
+			c.backend = &v1Backend{c: c}
+		default:
+			return nil, fmt.Errorf("detecting REST API version: %w", err)
+		}
+	}
+	return c.backend, nil
+}
@@ -0,0 +1,218 @@
+package confluence
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_FallsBackToV1(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v2/"):
+			http.NotFound(w, r)
+		case r.URL.Path == "/rest/api/content/123":
+			fmt.Fprint(w, `{"id":"123","title":"From v1","space":{"key":"ENG"},"version":{"number":4}}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user@example.com", "token", false)
+	page, err := client.GetPageByID("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Title != "From v1" || page.Space.Key != "ENG" || page.Version.Number != 4 {
+		t.Errorf("unexpected page: %+v", page)
+	}
+	if _, ok := client.backend.(*v1Backend); !ok {
+		t.Errorf("expected v1 backend, got %T", client.backend)
+	}
+}
+
+func TestClient_V2(t *testing.T) {
+	userLookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/wiki/rest/api/user" && r.URL.Query().Get("accountId") == "abc":
+			userLookups++
+			fmt.Fprint(w, `{"accountId":"abc","displayName":"Ada Lovelace"}`)
+		case r.URL.Path == "/wiki/api/v2/spaces" && r.URL.Query().Get("limit") == "1":
+			fmt.Fprint(w, `{"results":[]}`)
+		case r.URL.Path == "/wiki/api/v2/pages/123":
+			if got := r.URL.Query().Get("body-format"); got != "storage" {
+				t.Errorf("expected body-format=storage, got %q", got)
+			}
+			fmt.Fprint(w, `{"id":"123","title":"From v2","spaceId":"99","version":{"number":2,"authorId":"abc"},"body":{"storage":{"value":"<p>hi</p>","representation":"storage"}}}`)
+		case r.URL.Path == "/wiki/api/v2/spaces/99":
+			fmt.Fprint(w, `{"id":"99","key":"ENG","name":"Engineering"}`)
+		case r.URL.Path == "/wiki/api/v2/pages/123/versions":
+			if r.URL.Query().Get("cursor") == "" {
+				fmt.Fprint(w, `{"results":[{"number":2,"authorId":"abc"}],"_links":{"next":"/wiki/api/v2/pages/123/versions?limit=50&cursor=next"}}`)
+			} else {
+				fmt.Fprint(w, `{"results":[{"number":1}],"_links":{}}`)
+			}
+		case r.URL.Path == "/wiki/api/v2/pages/123/children":
+			if r.URL.Query().Get("cursor") == "" {
+				fmt.Fprint(w, `{"results":[{"id":"124","title":"Setup"}],"_links":{"next":"/wiki/api/v2/pages/123/children?limit=50&cursor=next"}}`)
+			} else {
+				fmt.Fprint(w, `{"results":[{"id":"125","title":"Deploy"}],"_links":{}}`)
+			}
+		case r.URL.Path == "/wiki/api/v2/pages/123/labels":
+			fmt.Fprint(w, `{"results":[{"prefix":"global","name":"runbook"},{"prefix":"my","name":"todo"},{"prefix":"team","name":"ops"}],"_links":{}}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/wiki", "user@example.com", "token", false)
+	page, err := client.GetPageByID("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Title != "From v2" || page.Space.Key != "ENG" || page.Body.Storage.Value != "<p>hi</p>" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+	if page.Version.By.Name() != "Ada Lovelace" {
+		t.Errorf("expected author display name, got %q", page.Version.By.Name())
+	}
+
+	versions, err := client.GetPageVersions("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Number != 2 || versions[1].Number != 1 {
+		t.Errorf("expected versions [2 1] across two pages, got %+v", versions)
+	}
+	if versions[0].By.Name() != "Ada Lovelace" || userLookups != 1 {
+		t.Errorf("expected version author resolved from one lookup, got %q after %d lookups", versions[0].By.Name(), userLookups)
+	}
+
+	children, err := client.GetChildPages("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(children) != 2 || children[0].Title != "Setup" || children[1].ID != "125" {
+		t.Errorf("expected children [Setup Deploy] across two pages, got %+v", children)
+	}
+
+	labels, err := client.GetLabels("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(labels, ",") != "runbook,ops" {
+		t.Errorf("expected labels [runbook ops] without personal labels, got %v", labels)
+	}
+}
+
+func TestClient_V1Lists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v2/"):
+			http.NotFound(w, r)
+		case r.URL.Path == "/rest/api/space/ENG/content/page":
+			if r.URL.Query().Get("depth") != "root" {
+				t.Errorf("expected depth=root, got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"results":[{"id":"1","title":"Home"}],"_links":{}}`)
+		case r.URL.Path == "/rest/api/content/123/child/comment":
+			if r.URL.Query().Get("location") != "inline" {
+				t.Errorf("expected location=inline, got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"results":[{"id":"9","body":{"storage":{"value":"<p>Why?</p>"}},"history":{"createdBy":{"displayName":"Jane Doe"}},"extensions":{"inlineProperties":{"markerRef":"ref-a","originalSelection":"scales"}}}],"_links":{}}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user@example.com", "token", false)
+	comments, err := client.GetInlineComments("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	roots, err := client.GetSpaceRootPages("ENG")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(roots) != 1 || roots[0].Title != "Home" {
+		t.Errorf("unexpected root pages: %+v", roots)
+	}
+
+	want := Comment{ID: "9", Body: "<p>Why?</p>", Author: User{DisplayName: "Jane Doe"}, MarkerRef: "ref-a", Selection: "scales"}
+	if len(comments) != 1 || comments[0] != want {
+		t.Errorf("unexpected comments: %+v", comments)
+	}
+}
+
+func TestClient_ProbeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"unauthorized", http.StatusUnauthorized},
+		{"server error", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v2/spaces" {
+					t.Errorf("unexpected request: %s", r.URL)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "user@example.com", "token", false)
+			if _, err := client.GetPageByID("123"); err == nil {
+				t.Fatal("expected the failed probe to be returned")
+			}
+			if client.backend != nil {
+				t.Errorf("expected no backend after a failed probe, got %T", client.backend)
+			}
+		})
+	}
+
+	client := NewClient("http://127.0.0.1:0", "user@example.com", "token", false)
+	if _, err := client.GetPageByID("123"); err == nil || client.backend != nil {
+		t.Errorf("expected a network error and no backend, got %v, %T", err, client.backend)
+	}
+}
+
+func TestClient_V2View(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/pages/123":
+			if got := r.URL.Query().Get("body-format"); got != "view" {
+				t.Errorf("expected body-format=view, got %q", got)
+			}
+			fmt.Fprint(w, `{"id":"123","title":"T","spaceId":"99","body":{"view":{"value":"<p>hi</p>","representation":"view"}}}`)
+		case "/api/v2/spaces/99":
+			fmt.Fprint(w, `{"id":"99","key":"ENG"}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user@example.com", "token", false)
+	client.APIVersion = APIV2
+	client.View = true
+	page, err := client.GetPageByID("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Body.View.Value != "<p>hi</p>" {
+		t.Errorf("expected view body, got %+v", page.Body)
+	}
+}
@@ -0,0 +1,144 @@
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/justinabrahms/confluence-md/internal/cache"
+)
+
+// ErrOffline is returned for requests an offline client can't serve from
+// its cache.
+var ErrOffline = errors.New("not available offline")
+
+// cachedRequest looks up the cached response to a GET request for fullURL
+// and makes req conditional on it. Offline, the cached response is
+// returned as resp instead, and requests without one fail.
+func (c *Client) cachedRequest(req *http.Request, fullURL string) (entry *cache.Entry, resp *http.Response, err error) {
+	if req.Method != http.MethodGet || c.Cache == nil {
+		if c.Offline {
+			return nil, nil, fmt.Errorf("%s %s: %w", req.Method, fullURL, ErrOffline)
+		}
+		return nil, nil, nil
+	}
+
+	entry = c.Cache.Get(c.Email, fullURL)
+	if c.Offline {
+		if entry == nil {
+			return nil, nil, fmt.Errorf("%s is not cached: %w", fullURL, ErrOffline)
+		}
+		c.debugf("Serving %s from the cache", fullURL)
+		resp, err := c.cachedResponse(entry)
+		return nil, resp, err
+	}
+
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	return entry, nil, nil
+}
+
+// storeResponse caches a response to a GET request for fullURL. Besides
+// successful responses, 404s are kept so that offline runs see the same
+// missing pages, and API versions, as online ones. resp's body is replaced
+// so it can still be read.
+func (c *Client) storeResponse(req *http.Request, fullURL string, resp *http.Response) error {
+	if req.Method != http.MethodGet || c.Cache == nil {
+		return nil
+	}
+	if (resp.StatusCode < 200 || resp.StatusCode >= 300) && resp.StatusCode != http.StatusNotFound {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := &cache.Entry{
+		URL:          fullURL,
+		Profile:      c.Email,
+		Status:       resp.StatusCode,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Version:      responseVersion(body),
+		Body:         body,
+	}
+	if err := c.Cache.Put(entry); err != nil {
+		c.debugf("Caching response: %v", err)
+	}
+	return nil
+}
+
+// cachedResponse turns a cached entry back into a response, or into the
+// HTTPError the original response produced.
+func (c *Client) cachedResponse(entry *cache.Entry) (*http.Response, error) {
+	c.Cache.Touch(entry)
+	if entry.Status < 200 || entry.Status >= 300 {
+		return nil, &HTTPError{StatusCode: entry.Status, Body: string(entry.Body)}
+	}
+	return &http.Response{
+		StatusCode: entry.Status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(entry.Body)),
+	}, nil
+}
+
+// responseVersion returns the page version number in a page response from
+// either API version, or 0 for other responses.
+func responseVersion(body []byte) int {
+	var page struct {
+		Version struct {
+			Number int `json:"number"`
+		} `json:"version"`
+	}
+	if json.Unmarshal(body, &page) != nil {
+		return 0
+	}
+	return page.Version.Number
+}
+
+// doPageRequest sends a GET request for path, which fetches page pageID
+// at a historical version when version > 0. A cached response holding the
+// wanted version is used without downloading the page again: historical
+// versions never change, and the page's current version number is looked
+// up with a request much smaller than the page itself.
+func (c *Client) doPageRequest(path, pageID string, version int) (*http.Response, error) {
+	if c.Cache == nil || c.Offline {
+		return c.doRequest("GET", path)
+	}
+
+	entry := c.Cache.Get(c.Email, c.BaseURL+path)
+	if entry == nil || entry.Status != http.StatusOK || entry.Version == 0 {
+		return c.doRequest("GET", path)
+	}
+	if version == 0 {
+		api, err := c.api()
+		if err != nil {
+			return nil, err
+		}
+		current, err := api.currentVersion(pageID)
+		if err != nil {
+			c.debugf("Looking up current version of page %s: %v", pageID, err)
+			return c.doRequest("GET", path)
+		}
+		version = current
+	}
+	if entry.Version != version {
+		return c.doRequest("GET", path)
+	}
+
+	c.debugf("Page %s is still at version %d, using cached copy", pageID, version)
+	return c.cachedResponse(entry)
+}
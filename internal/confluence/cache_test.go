@@ -0,0 +1,155 @@
+package confluence
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/cache"
+)
+
+func newCachedClient(t *testing.T, url string) *Client {
+	t.Helper()
+	store, err := cache.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := NewClient(url, "user@example.com", "token", false)
+	client.APIVersion = APIV1
+	client.Cache = store
+	return client
+}
+
+func TestClient_CacheSkipsUnchangedPages(t *testing.T) {
+	version, fullFetches := 4, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/content/123" {
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("expand") == "version" {
+			fmt.Fprintf(w, `{"id":"123","version":{"number":%d}}`, version)
+			return
+		}
+		fullFetches++
+		fmt.Fprintf(w, `{"id":"123","title":"Runbook v%d","version":{"number":%d}}`, version, version)
+	}))
+	defer server.Close()
+
+	client := newCachedClient(t, server.URL)
+	for i := 0; i < 2; i++ {
+		page, err := client.GetPageByID("123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if page.Title != "Runbook v4" {
+			t.Errorf("unexpected page: %+v", page)
+		}
+	}
+	if fullFetches != 1 {
+		t.Errorf("expected the unchanged page to be fetched once, got %d fetches", fullFetches)
+	}
+
+	version = 5
+	page, err := client.GetPageByID("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Title != "Runbook v5" || fullFetches != 2 {
+		t.Errorf("expected the changed page to be fetched again, got %+v after %d fetches", page, fullFetches)
+	}
+}
+
+func TestClient_CacheRevalidates(t *testing.T) {
+	notModified := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"abc"`)
+		fmt.Fprint(w, `{"results":[{"prefix":"global","name":"runbook"}],"_links":{}}`)
+	}))
+	defer server.Close()
+
+	client := newCachedClient(t, server.URL)
+	for i := 0; i < 2; i++ {
+		labels, err := client.GetLabels("123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(labels) != 1 || labels[0] != "runbook" {
+			t.Errorf("unexpected labels: %v", labels)
+		}
+	}
+	if notModified != 1 {
+		t.Errorf("expected one 304 response, got %d", notModified)
+	}
+}
+
+func TestClient_Offline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/content/123":
+			fmt.Fprint(w, `{"id":"123","title":"Runbook","version":{"number":1}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	client := newCachedClient(t, server.URL)
+	if _, err := client.GetPageByID("123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GetPageByID("999"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	server.Close()
+
+	client.Offline = true
+	page, err := client.GetPageByID("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Title != "Runbook" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+	if _, err := client.GetPageByID("999"); !IsNotFound(err) {
+		t.Errorf("expected cached not found, got %v", err)
+	}
+	if _, err := client.GetPageByID("456"); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected ErrOffline for an uncached page, got %v", err)
+	}
+	if _, err := client.UpdatePage("123", "Runbook", "<p>x</p>", 2, ""); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected ErrOffline for an update, got %v", err)
+	}
+}
+
+func TestClient_CacheSkipsAttachmentDownloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/content/123/child/attachment":
+			fmt.Fprint(w, `{"results":[{"id":"att1","title":"flow.png","_links":{"download":"/download/attachments/123/flow.png"}}]}`)
+		case "/download/attachments/123/flow.png":
+			fmt.Fprint(w, "PNGDATA")
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := newCachedClient(t, server.URL)
+	if _, err := client.GetAttachment("123", "flow.png"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.Cache.Get(client.Email, server.URL+"/download/attachments/123/flow.png") != nil {
+		t.Error("expected the attachment download to bypass the cache")
+	}
+	if client.Cache.Get(client.Email, server.URL+"/rest/api/content/123/child/attachment?filename=flow.png&expand=version") == nil {
+		t.Error("expected the attachment lookup to be cached")
+	}
+}
@@ -1,6 +1,7 @@
 package confluence
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/justinabrahms/confluence-md/internal/cache"
 )
 
 type Client struct {
@@ -18,7 +21,14 @@ type Client struct {
 	APIToken   string
 	HTTPClient *http.Client
 	Debug      bool
+	ADF        bool         // also request atlas_doc_format page bodies
+	View       bool         // request view bodies from v2, which returns one body format
+	APIVersion string       // APIAuto, APIV1 or APIV2
+	Cache      *cache.Cache // reuse and revalidate GET responses; nil disables
+	Offline    bool         // serve GET requests from Cache only
 	logger     *log.Logger
+	backend    pageBackend
+	users      map[UserRef]*User
 }
 
 type Page struct {
@@ -29,13 +39,14 @@ type Page struct {
 	Body    Body      `json:"body"`
 	Version Version   `json:"version"`
 	History History   `json:"history"`
-	Space   Space     `json:"_expandable"`
+	Space   Space     `json:"space"`
 	Links   Links     `json:"_links"`
 }
 
 type Body struct {
-	Storage Storage `json:"storage"`
-	View    Storage `json:"view"`
+	Storage        Storage `json:"storage"`
+	View           Storage `json:"view"`
+	AtlasDocFormat Storage `json:"atlas_doc_format"`
 }
 
 type Storage struct {
@@ -53,6 +64,24 @@ type Version struct {
 type User struct {
 	DisplayName string `json:"displayName"`
 	Email       string `json:"email"`
+	AccountID   string `json:"accountId"`
+	Username    string `json:"username"` // Data Center only
+	UserKey     string `json:"userKey"`  // Data Center only
+
+	// ProfileURL is the user's profile page, filled in by GetUser.
+	ProfileURL string `json:"-"`
+}
+
+// Name returns the user's display name, or their username or account ID
+// when the API didn't include one (the v2 API only returns IDs).
+func (u User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Username != "" {
+		return u.Username
+	}
+	return u.AccountID
 }
 
 type History struct {
@@ -66,8 +95,10 @@ type Space struct {
 }
 
 type Links struct {
-	WebUI string `json:"webui"`
-	Self  string `json:"self"`
+	WebUI    string `json:"webui"`
+	Self     string `json:"self"`
+	Next     string `json:"next"`
+	Download string `json:"download"`
 }
 
 type SearchResult struct {
@@ -77,6 +108,14 @@ type SearchResult struct {
 	Size    int                `json:"size"`
 }
 
+type VersionList struct {
+	Results []Version `json:"results"`
+	Start   int       `json:"start"`
+	Limit   int       `json:"limit"`
+	Size    int       `json:"size"`
+	Links   Links     `json:"_links"`
+}
+
 type SearchResultItem struct {
 	ID            string    `json:"id"`
 	Type          string    `json:"type"`
@@ -101,8 +140,9 @@ func NewClient(baseURL, email, apiToken string, debug bool) *Client {
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		Debug:  debug,
-		logger: logger,
+		Debug:      debug,
+		APIVersion: APIAuto,
+		logger:     logger,
 	}
 }
 
@@ -113,29 +153,88 @@ func (c *Client) debugf(format string, args ...interface{}) {
 }
 
 func (c *Client) doRequest(method, path string) (*http.Response, error) {
+	return c.doRequestWithBody(method, path, nil)
+}
+
+// doRequestWithBody sends payload, if non-nil, as a JSON request body.
+func (c *Client) doRequestWithBody(method, path string, payload interface{}) (*http.Response, error) {
 	fullURL := c.BaseURL + path
 	c.debugf("Request: %s %s", method, fullURL)
 
-	req, err := http.NewRequest(method, fullURL, nil)
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fullURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	req.SetBasicAuth(c.Email, c.APIToken)
 	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
-	resp, err := c.HTTPClient.Do(req)
+	cached, resp, err := c.cachedRequest(req, fullURL)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	resp, err = c.HTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 
 	c.debugf("Response: HTTP %d", resp.StatusCode)
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		c.debugf("Not modified, using cached response")
+		return c.cachedResponse(cached)
+	}
+	if err := c.storeResponse(req, fullURL, resp); err != nil {
+		return nil, err
+	}
+	return c.checkResponse(resp)
+}
+
+// doDownload sends a GET request for a file such as an attachment. Files
+// bypass the response cache, which holds API responses: they can be large
+// and callers save them to disk anyway.
+func (c *Client) doDownload(path string) (*http.Response, error) {
+	fullURL := c.BaseURL + path
+	if c.Offline {
+		return nil, fmt.Errorf("GET %s: %w", fullURL, ErrOffline)
+	}
+	c.debugf("Request: GET %s", fullURL)
+
+	req, err := http.NewRequest("GET", fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.SetBasicAuth(c.Email, c.APIToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	c.debugf("Response: HTTP %d", resp.StatusCode)
+	return c.checkResponse(resp)
+}
+
+// checkResponse turns a non-2xx response into an HTTPError.
+func (c *Client) checkResponse(resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		c.debugf("Error response body: %s", string(body))
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return resp, nil
@@ -143,31 +242,214 @@ func (c *Client) doRequest(method, path string) (*http.Response, error) {
 
 func (c *Client) GetPageByID(pageID string) (*Page, error) {
 	c.debugf("Fetching page by ID: %s", pageID)
-	path := fmt.Sprintf("/rest/api/content/%s?expand=body.storage,body.view,version,history,space", pageID)
 
-	resp, err := c.doRequest("GET", path)
+	api, err := c.api()
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var page Page
-	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	page, err := api.getPage(pageID, 0)
+	if err != nil {
+		return nil, err
 	}
 
 	c.debugf("Successfully fetched page: %s (ID: %s)", page.Title, page.ID)
-	return &page, nil
+	return page, nil
 }
 
 func (c *Client) GetPageByURL(pageURL string) (*Page, error) {
-	pageID, err := extractPageIDFromURL(pageURL)
+	pageID, err := PageIDFromURL(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetPageByID(pageID)
+}
+
+// Comment is a comment on a page. Inline comments carry the ac:ref of the
+// ac:inline-comment-marker around the text they annotate.
+type Comment struct {
+	ID        string
+	Body      string // storage format
+	Author    User
+	MarkerRef string
+	Selection string
+}
+
+// GetInlineComments fetches the inline comments on a page.
+func (c *Client) GetInlineComments(pageID string) ([]Comment, error) {
+	c.debugf("Fetching inline comments for page %s", pageID)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	comments, err := api.getInlineComments(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.debugf("Found %d inline comments", len(comments))
+	return comments, nil
+}
+
+// GetChildPages lists the direct children of a page in their Confluence
+// order. Only their ID and title are filled in; fetch each page for its
+// body.
+func (c *Client) GetChildPages(pageID string) ([]Page, error) {
+	c.debugf("Fetching child pages of page %s", pageID)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	pages, err := api.getChildPages(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.debugf("Found %d child pages", len(pages))
+	return pages, nil
+}
+
+// GetSpaceRootPages lists the top-level pages of a space, usually just its
+// home page. Like GetChildPages, only their ID and title are filled in.
+func (c *Client) GetSpaceRootPages(spaceKey string) ([]Page, error) {
+	c.debugf("Fetching top-level pages of space %s", spaceKey)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	pages, err := api.getRootPages(spaceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.debugf("Found %d top-level pages", len(pages))
+	return pages, nil
+}
+
+// Label is a label on a page. Personal labels have the prefix "my".
+type Label struct {
+	Prefix string `json:"prefix"`
+	Name   string `json:"name"`
+}
+
+// labelNames returns the names of the global and team labels, leaving out
+// personal ones.
+func labelNames(labels []Label) []string {
+	var names []string
+	for _, l := range labels {
+		if l.Prefix != "my" {
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+// GetLabels lists the names of a page's labels.
+func (c *Client) GetLabels(pageID string) ([]string, error) {
+	c.debugf("Fetching labels for page %s", pageID)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	labels, err := api.getLabels(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.debugf("Found %d labels", len(labels))
+	return labels, nil
+}
+
+// GetPageByTitle fetches the page with the given title in a space.
+func (c *Client) GetPageByTitle(spaceKey, title string) (*Page, error) {
+	c.debugf("Fetching page by title: %q in space %s", title, spaceKey)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	pageID, err := api.findPageID(spaceKey, title)
 	if err != nil {
 		return nil, err
 	}
 	return c.GetPageByID(pageID)
 }
 
+// GetPageVersion fetches a page as it was at the given version number.
+func (c *Client) GetPageVersion(pageID string, version int) (*Page, error) {
+	c.debugf("Fetching page %s at version %d", pageID, version)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	page, err := api.getPage(pageID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	c.debugf("Successfully fetched page: %s (ID: %s, v%d)", page.Title, page.ID, page.Version.Number)
+	return page, nil
+}
+
+// GetPageVersions lists every version of a page, following pagination
+// until the server reports no further results.
+func (c *Client) GetPageVersions(pageID string) ([]Version, error) {
+	c.debugf("Fetching version history for page: %s", pageID)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := api.getVersions(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.debugf("Found %d versions", len(versions))
+	return versions, nil
+}
+
+// CreatePage creates a new page in the given space from a storage-format
+// body. parentID may be empty to create the page at the space root.
+func (c *Client) CreatePage(spaceKey, parentID, title, storage string) (*Page, error) {
+	c.debugf("Creating page %q in space %s (parent: %s)", title, spaceKey, parentID)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	page, err := api.createPage(spaceKey, parentID, title, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	c.debugf("Created page: %s (ID: %s)", page.Title, page.ID)
+	return page, nil
+}
+
+// UpdatePage replaces the body and title of an existing page. version must
+// be exactly one more than the page's current version number, otherwise
+// Confluence rejects the update with a conflict.
+func (c *Client) UpdatePage(pageID, title, storage string, version int, message string) (*Page, error) {
+	c.debugf("Updating page %s to version %d", pageID, version)
+
+	api, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	page, err := api.updatePage(pageID, title, storage, version, message)
+	if err != nil {
+		return nil, err
+	}
+
+	c.debugf("Updated page: %s (ID: %s, v%d)", page.Title, page.ID, page.Version.Number)
+	return page, nil
+}
+
 func (c *Client) Search(query string, spaceKey string, limit int, mine bool, userEmail string) (*SearchResult, error) {
 	params := url.Values{}
 
@@ -224,7 +506,8 @@ func (c *Client) Search(query string, spaceKey string, limit int, mine bool, use
 	return &result, nil
 }
 
-func extractPageIDFromURL(pageURL string) (string, error) {
+// PageIDFromURL extracts the numeric page ID from a Confluence page URL.
+func PageIDFromURL(pageURL string) (string, error) {
 	u, err := url.Parse(pageURL)
 	if err != nil {
 		return "", fmt.Errorf("invalid URL: %w", err)
@@ -0,0 +1,131 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// JiraClient talks to the REST API of a Jira site linked to Confluence,
+// reusing the Confluence client's HTTP client and credentials.
+type JiraClient struct {
+	c      *Client
+	issues map[string]*JiraIssue
+}
+
+// JiraIssue is an issue returned by the Jira REST API. Fields holds the
+// requested fields as decoded JSON.
+type JiraIssue struct {
+	Key    string                 `json:"key"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type jiraSearchResult struct {
+	Issues []JiraIssue `json:"issues"`
+}
+
+// Jira returns a client for the Jira site at baseURL that authenticates
+// the same way as c.
+func (c *Client) Jira(baseURL string) *JiraClient {
+	return &JiraClient{
+		c: &Client{
+			BaseURL:    strings.TrimSuffix(baseURL, "/"),
+			Email:      c.Email,
+			APIToken:   c.APIToken,
+			HTTPClient: c.HTTPClient,
+			Debug:      c.Debug,
+			Cache:      c.Cache,
+			Offline:    c.Offline,
+			logger:     c.logger,
+		},
+		issues: map[string]*JiraIssue{},
+	}
+}
+
+// BaseURL returns the Jira site's base URL.
+func (j *JiraClient) BaseURL() string {
+	return j.c.BaseURL
+}
+
+// GetIssue fetches an issue's summary and status by key.
+func (j *JiraClient) GetIssue(key string) (*JiraIssue, error) {
+	if issue, ok := j.issues[key]; ok {
+		return issue, nil
+	}
+
+	j.c.debugf("Fetching Jira issue: %s", key)
+
+	resp, err := j.c.doRequest("GET", "/rest/api/2/issue/"+url.PathEscape(key)+"?fields=summary,status")
+	if err != nil {
+		return nil, fmt.Errorf("fetching Jira issue %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	var issue JiraIssue
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		return nil, fmt.Errorf("decoding Jira issue: %w", err)
+	}
+	j.issues[key] = &issue
+	return &issue, nil
+}
+
+// SearchIssues runs a JQL query and returns up to limit issues with the
+// given fields. It uses the enhanced search endpoint on Jira Cloud and
+// falls back to /rest/api/2/search on Data Center.
+func (j *JiraClient) SearchIssues(jql string, fields []string, limit int) ([]JiraIssue, error) {
+	params := url.Values{}
+	params.Set("jql", jql)
+	params.Set("fields", strings.Join(fields, ","))
+	params.Set("maxResults", strconv.Itoa(limit))
+
+	j.c.debugf("Searching Jira: %s", jql)
+
+	resp, err := j.c.doRequest("GET", "/rest/api/3/search/jql?"+params.Encode())
+	if IsNotFound(err) {
+		resp, err = j.c.doRequest("GET", "/rest/api/2/search?"+params.Encode())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("searching Jira: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result jiraSearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding Jira search results: %w", err)
+	}
+	return result.Issues, nil
+}
+
+// Field returns a field's value as display text. Objects such as status,
+// priority and users are shown by their name.
+func (i JiraIssue) Field(name string) string {
+	return jiraFieldText(i.Fields[name])
+}
+
+func jiraFieldText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]interface{}:
+		for _, key := range []string{"displayName", "name", "value", "key"} {
+			if s, ok := v[key].(string); ok {
+				return s
+			}
+		}
+	case []interface{}:
+		var parts []string
+		for _, item := range v {
+			if s := jiraFieldText(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package confluence
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJiraClient_SearchFallsBackToV2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "user@example.com" {
+			t.Errorf("expected Confluence credentials, got user %q", user)
+		}
+		switch r.URL.Path {
+		case "/rest/api/3/search/jql":
+			http.NotFound(w, r)
+		case "/rest/api/2/search":
+			if got := r.URL.Query().Get("jql"); got != "project = ENG" {
+				t.Errorf("unexpected jql %q", got)
+			}
+			fmt.Fprint(w, `{"issues":[{"key":"ENG-1","fields":{"summary":"Fix login","assignee":{"displayName":"Jane Doe"},"labels":["a","b"]}}]}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	jira := NewClient("https://example.atlassian.net/wiki", "user@example.com", "token", false).Jira(server.URL)
+	issues, err := jira.SearchIssues("project = ENG", []string{"summary", "assignee", "labels"}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	issue := issues[0]
+	if issue.Key != "ENG-1" || issue.Field("summary") != "Fix login" || issue.Field("assignee") != "Jane Doe" || issue.Field("labels") != "a, b" {
+		t.Errorf("unexpected issue: %+v", issue)
+	}
+}
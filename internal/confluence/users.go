@@ -0,0 +1,80 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// UserRef identifies a user as referenced from storage format: by account
+// ID on Cloud, or by username or user key on Data Center.
+type UserRef struct {
+	AccountID string
+	Username  string
+	UserKey   string
+}
+
+// String returns whichever identifier the reference carries.
+func (r UserRef) String() string {
+	switch {
+	case r.AccountID != "":
+		return r.AccountID
+	case r.Username != "":
+		return r.Username
+	}
+	return r.UserKey
+}
+
+// GetUser looks up a user by account ID, username or user key. Results are
+// cached for the lifetime of the client, since the same people tend to be
+// mentioned throughout a page.
+func (c *Client) GetUser(ref UserRef) (*User, error) {
+	if u, ok := c.users[ref]; ok {
+		return u, nil
+	}
+
+	params := url.Values{}
+	switch {
+	case ref.AccountID != "":
+		params.Set("accountId", ref.AccountID)
+	case ref.Username != "":
+		params.Set("username", ref.Username)
+	case ref.UserKey != "":
+		params.Set("key", ref.UserKey)
+	default:
+		return nil, fmt.Errorf("empty user reference")
+	}
+
+	c.debugf("Looking up user: %s", ref)
+
+	resp, err := c.doRequest("GET", "/rest/api/user?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("looking up user %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+
+	var u User
+	if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
+		return nil, fmt.Errorf("decoding user: %w", err)
+	}
+	u.ProfileURL = c.profileURL(u)
+
+	if c.users == nil {
+		c.users = map[UserRef]*User{}
+	}
+	c.users[ref] = &u
+	return &u, nil
+}
+
+// profileURL returns the web UI address of a user's profile.
+func (c *Client) profileURL(u User) string {
+	switch {
+	case u.AccountID != "":
+		return c.BaseURL + "/people/" + url.PathEscape(u.AccountID)
+	case u.Username != "":
+		return c.BaseURL + "/display/~" + url.PathEscape(u.Username)
+	case u.UserKey != "":
+		return c.BaseURL + "/users/viewuserprofile.action?userKey=" + url.QueryEscape(u.UserKey)
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package confluence
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetUser(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/wiki/rest/api/user" {
+			t.Errorf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		switch q := r.URL.Query(); {
+		case q.Get("accountId") == "5b10ac":
+			fmt.Fprint(w, `{"accountId":"5b10ac","displayName":"Jane Doe"}`)
+		case q.Get("username") == "jsmith":
+			fmt.Fprint(w, `{"username":"jsmith","userKey":"ff80","displayName":"John Smith"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/wiki", "user@example.com", "token", false)
+
+	tests := []struct {
+		ref         UserRef
+		wantName    string
+		wantProfile string
+	}{
+		{UserRef{AccountID: "5b10ac"}, "Jane Doe", server.URL + "/wiki/people/5b10ac"},
+		{UserRef{Username: "jsmith"}, "John Smith", server.URL + "/wiki/display/~jsmith"},
+	}
+	for _, tt := range tests {
+		u, err := client.GetUser(tt.ref)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.ref, err)
+		}
+		if u.Name() != tt.wantName || u.ProfileURL != tt.wantProfile {
+			t.Errorf("got %q %q, want %q %q", u.Name(), u.ProfileURL, tt.wantName, tt.wantProfile)
+		}
+	}
+
+	if _, err := client.GetUser(UserRef{AccountID: "5b10ac"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected cached lookup, got %d requests", requests)
+	}
+
+	if _, err := client.GetUser(UserRef{AccountID: "missing"}); !IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// v1Backend talks to the /rest/api/content endpoints available on both
+// Confluence Cloud and Data Center.
+type v1Backend struct {
+	c *Client
+}
+
+// pageRequest is the body of create and update requests to /rest/api/content.
+type pageRequest struct {
+	ID        string        `json:"id,omitempty"`
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Space     *spaceRef     `json:"space,omitempty"`
+	Ancestors []ancestorRef `json:"ancestors,omitempty"`
+	Version   *versionRef   `json:"version,omitempty"`
+	Body      requestBody   `json:"body"`
+}
+
+type spaceRef struct {
+	Key string `json:"key"`
+}
+
+type ancestorRef struct {
+	ID string `json:"id"`
+}
+
+type versionRef struct {
+	Number  int    `json:"number"`
+	Message string `json:"message,omitempty"`
+}
+
+type requestBody struct {
+	Storage Storage `json:"storage"`
+}
+
+func storageBody(value string) requestBody {
+	return requestBody{Storage: Storage{Value: value, Representation: "storage"}}
+}
+
+// pageExpand returns the expand parameter used when fetching page content.
+func (b *v1Backend) pageExpand() string {
+	expand := "body.storage,body.view,version,history,space"
+	if b.c.ADF {
+		expand += ",body.atlas_doc_format"
+	}
+	return expand
+}
+
+func (b *v1Backend) getPage(pageID string, version int) (*Page, error) {
+	path := fmt.Sprintf("/rest/api/content/%s?expand=%s", pageID, b.pageExpand())
+	if version > 0 {
+		path = fmt.Sprintf("/rest/api/content/%s?status=historical&version=%d&expand=%s", pageID, version, b.pageExpand())
+	}
+
+	resp, err := b.c.doPageRequest(path, pageID, version)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var page Page
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &page, nil
+}
+
+func (b *v1Backend) currentVersion(pageID string) (int, error) {
+	resp, err := b.c.doRequest("GET", "/rest/api/content/"+pageID+"?expand=version")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var page Page
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return 0, fmt.Errorf("decoding response: %w", err)
+	}
+	return page.Version.Number, nil
+}
+
+func (b *v1Backend) getVersions(pageID string) ([]Version, error) {
+	const pageSize = 50
+	var versions []Version
+	for start := 0; ; start += pageSize {
+		path := fmt.Sprintf("/rest/api/content/%s/version?start=%d&limit=%d", pageID, start, pageSize)
+
+		resp, err := b.c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var list VersionList
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding version history: %w", err)
+		}
+
+		versions = append(versions, list.Results...)
+		if list.Links.Next == "" || len(list.Results) < pageSize {
+			break
+		}
+	}
+	return versions, nil
+}
+
+type v1Comment struct {
+	ID         string  `json:"id"`
+	Body       Body    `json:"body"`
+	History    History `json:"history"`
+	Extensions struct {
+		InlineProperties struct {
+			MarkerRef         string `json:"markerRef"`
+			OriginalSelection string `json:"originalSelection"`
+		} `json:"inlineProperties"`
+	} `json:"extensions"`
+}
+
+func (b *v1Backend) getInlineComments(pageID string) ([]Comment, error) {
+	const pageSize = 50
+	var comments []Comment
+	for start := 0; ; start += pageSize {
+		path := fmt.Sprintf("/rest/api/content/%s/child/comment?location=inline&expand=body.storage,history,extensions.inlineProperties&start=%d&limit=%d",
+			pageID, start, pageSize)
+
+		resp, err := b.c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var list struct {
+			Results []v1Comment `json:"results"`
+			Links   Links       `json:"_links"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding comments: %w", err)
+		}
+
+		for _, c := range list.Results {
+			comments = append(comments, Comment{
+				ID:        c.ID,
+				Body:      c.Body.Storage.Value,
+				Author:    c.History.CreatedBy,
+				MarkerRef: c.Extensions.InlineProperties.MarkerRef,
+				Selection: c.Extensions.InlineProperties.OriginalSelection,
+			})
+		}
+		if list.Links.Next == "" || len(list.Results) < pageSize {
+			break
+		}
+	}
+	return comments, nil
+}
+
+func (b *v1Backend) getChildPages(pageID string) ([]Page, error) {
+	const pageSize = 50
+	var pages []Page
+	for start := 0; ; start += pageSize {
+		path := fmt.Sprintf("/rest/api/content/%s/child/page?start=%d&limit=%d", pageID, start, pageSize)
+
+		resp, err := b.c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var list struct {
+			Results []Page `json:"results"`
+			Links   Links  `json:"_links"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding child pages: %w", err)
+		}
+
+		pages = append(pages, list.Results...)
+		if list.Links.Next == "" || len(list.Results) < pageSize {
+			break
+		}
+	}
+	return pages, nil
+}
+
+func (b *v1Backend) getRootPages(spaceKey string) ([]Page, error) {
+	const pageSize = 50
+	var pages []Page
+	for start := 0; ; start += pageSize {
+		path := fmt.Sprintf("/rest/api/space/%s/content/page?depth=root&start=%d&limit=%d", url.PathEscape(spaceKey), start, pageSize)
+
+		resp, err := b.c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var list struct {
+			Results []Page `json:"results"`
+			Links   Links  `json:"_links"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding space pages: %w", err)
+		}
+
+		pages = append(pages, list.Results...)
+		if list.Links.Next == "" || len(list.Results) < pageSize {
+			break
+		}
+	}
+	return pages, nil
+}
+
+func (b *v1Backend) getLabels(pageID string) ([]string, error) {
+	const pageSize = 200
+	var labels []string
+	for start := 0; ; start += pageSize {
+		path := fmt.Sprintf("/rest/api/content/%s/label?start=%d&limit=%d", pageID, start, pageSize)
+
+		resp, err := b.c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var list struct {
+			Results []Label `json:"results"`
+			Links   Links   `json:"_links"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding labels: %w", err)
+		}
+
+		labels = append(labels, labelNames(list.Results)...)
+		if list.Links.Next == "" || len(list.Results) < pageSize {
+			break
+		}
+	}
+	return labels, nil
+}
+
+func (b *v1Backend) findPageID(spaceKey, title string) (string, error) {
+	params := url.Values{}
+	params.Set("type", "page")
+	params.Set("spaceKey", spaceKey)
+	params.Set("title", title)
+
+	resp, err := b.c.doRequest("GET", "/rest/api/content?"+params.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result SearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return "", fmt.Errorf("page %q not found in space %s", title, spaceKey)
+	}
+	return result.Results[0].ID, nil
+}
+
+func (b *v1Backend) createPage(spaceKey, parentID, title, storage string) (*Page, error) {
+	req := pageRequest{
+		Type:  "page",
+		Title: title,
+		Space: &spaceRef{Key: spaceKey},
+		Body:  storageBody(storage),
+	}
+	if parentID != "" {
+		req.Ancestors = []ancestorRef{{ID: parentID}}
+	}
+
+	resp, err := b.c.doRequestWithBody("POST", "/rest/api/content", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var page Page
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &page, nil
+}
+
+func (b *v1Backend) updatePage(pageID, title, storage string, version int, message string) (*Page, error) {
+	req := pageRequest{
+		ID:      pageID,
+		Type:    "page",
+		Title:   title,
+		Version: &versionRef{Number: version, Message: message},
+		Body:    storageBody(storage),
+	}
+
+	resp, err := b.c.doRequestWithBody("PUT", "/rest/api/content/"+pageID, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var page Page
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &page, nil
+}
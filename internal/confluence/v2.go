@@ -0,0 +1,372 @@
+package confluence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// v2Backend talks to the Confluence Cloud REST API v2 (/api/v2/pages,
+// /api/v2/spaces), which replaces the /rest/api/content endpoints.
+type v2Backend struct {
+	c *Client
+
+	// spaces caches space lookups by ID for the lifetime of the client.
+	spaces map[string]Space
+}
+
+type v2Page struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	Title     string    `json:"title"`
+	SpaceID   string    `json:"spaceId"`
+	ParentID  string    `json:"parentId"`
+	AuthorID  string    `json:"authorId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Version   v2Version `json:"version"`
+	Body      Body      `json:"body"`
+	Links     Links     `json:"_links"`
+}
+
+type v2Version struct {
+	Number    int       `json:"number"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"createdAt"`
+	AuthorID  string    `json:"authorId"`
+}
+
+type v2Space struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// v2List is the cursor-paginated envelope used by v2 list endpoints.
+type v2List[T any] struct {
+	Results []T   `json:"results"`
+	Links   Links `json:"_links"`
+}
+
+type v2PageRequest struct {
+	ID       string      `json:"id,omitempty"`
+	SpaceID  string      `json:"spaceId,omitempty"`
+	Status   string      `json:"status"`
+	Title    string      `json:"title"`
+	ParentID string      `json:"parentId,omitempty"`
+	Body     Storage     `json:"body"`
+	Version  *versionRef `json:"version,omitempty"`
+}
+
+func newV2Backend(c *Client) *v2Backend {
+	return &v2Backend{c: c, spaces: map[string]Space{}}
+}
+
+// bodyFormat selects the single body representation v2 returns.
+func (b *v2Backend) bodyFormat() string {
+	switch {
+	case b.c.ADF:
+		return "atlas_doc_format"
+	case b.c.View:
+		return "view"
+	}
+	return "storage"
+}
+
+func (b *v2Backend) getPage(pageID string, version int) (*Page, error) {
+	params := url.Values{}
+	params.Set("body-format", b.bodyFormat())
+	if version > 0 {
+		params.Set("version", fmt.Sprintf("%d", version))
+	}
+
+	resp, err := b.c.doPageRequest("/api/v2/pages/"+pageID+"?"+params.Encode(), pageID, version)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var p v2Page
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	space, err := b.space(p.SpaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{
+		ID:     p.ID,
+		Type:   "page",
+		Status: p.Status,
+		Title:  p.Title,
+		Body:   p.Body,
+		Version: Version{
+			Number:  p.Version.Number,
+			When:    p.Version.CreatedAt,
+			Message: p.Version.Message,
+			By:      b.author(p.Version.AuthorID),
+		},
+		History: History{
+			CreatedDate: p.CreatedAt,
+			CreatedBy:   b.author(p.AuthorID),
+		},
+		Space: space,
+		Links: p.Links,
+	}, nil
+}
+
+// currentVersion fetches the page without its body.
+func (b *v2Backend) currentVersion(pageID string) (int, error) {
+	var p v2Page
+	if err := b.getJSON("/api/v2/pages/"+pageID, &p); err != nil {
+		return 0, err
+	}
+	return p.Version.Number, nil
+}
+
+func (b *v2Backend) getVersions(pageID string) ([]Version, error) {
+	var versions []Version
+	path := "/api/v2/pages/" + pageID + "/versions?limit=50"
+	for path != "" {
+		var list v2List[v2Version]
+		if err := b.getJSON(path, &list); err != nil {
+			return nil, err
+		}
+		for _, v := range list.Results {
+			versions = append(versions, Version{
+				Number:  v.Number,
+				When:    v.CreatedAt,
+				Message: v.Message,
+				By:      b.author(v.AuthorID),
+			})
+		}
+		path = b.nextPath(list.Links.Next)
+	}
+	return versions, nil
+}
+
+type v2InlineComment struct {
+	ID         string    `json:"id"`
+	Version    v2Version `json:"version"`
+	Body       Body      `json:"body"`
+	Properties struct {
+		InlineMarkerRef         string `json:"inlineMarkerRef"`
+		InlineOriginalSelection string `json:"inlineOriginalSelection"`
+	} `json:"properties"`
+}
+
+func (b *v2Backend) getInlineComments(pageID string) ([]Comment, error) {
+	var comments []Comment
+	path := "/api/v2/pages/" + pageID + "/inline-comments?body-format=storage&limit=50"
+	for path != "" {
+		var list v2List[v2InlineComment]
+		if err := b.getJSON(path, &list); err != nil {
+			return nil, err
+		}
+		for _, c := range list.Results {
+			comments = append(comments, Comment{
+				ID:        c.ID,
+				Body:      c.Body.Storage.Value,
+				Author:    b.author(c.Version.AuthorID),
+				MarkerRef: c.Properties.InlineMarkerRef,
+				Selection: c.Properties.InlineOriginalSelection,
+			})
+		}
+		path = b.nextPath(list.Links.Next)
+	}
+	return comments, nil
+}
+
+func (b *v2Backend) getChildPages(pageID string) ([]Page, error) {
+	var pages []Page
+	path := "/api/v2/pages/" + pageID + "/children?limit=50"
+	for path != "" {
+		var list v2List[v2Page]
+		if err := b.getJSON(path, &list); err != nil {
+			return nil, err
+		}
+		for _, p := range list.Results {
+			pages = append(pages, Page{ID: p.ID, Type: "page", Status: p.Status, Title: p.Title})
+		}
+		path = b.nextPath(list.Links.Next)
+	}
+	return pages, nil
+}
+
+func (b *v2Backend) getRootPages(spaceKey string) ([]Page, error) {
+	spaceID, err := b.spaceIDForKey(spaceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []Page
+	path := "/api/v2/spaces/" + spaceID + "/pages?depth=root&limit=50"
+	for path != "" {
+		var list v2List[v2Page]
+		if err := b.getJSON(path, &list); err != nil {
+			return nil, err
+		}
+		for _, p := range list.Results {
+			pages = append(pages, Page{ID: p.ID, Type: "page", Status: p.Status, Title: p.Title})
+		}
+		path = b.nextPath(list.Links.Next)
+	}
+	return pages, nil
+}
+
+func (b *v2Backend) getLabels(pageID string) ([]string, error) {
+	var labels []string
+	path := "/api/v2/pages/" + pageID + "/labels?limit=250"
+	for path != "" {
+		var list v2List[Label]
+		if err := b.getJSON(path, &list); err != nil {
+			return nil, err
+		}
+		labels = append(labels, labelNames(list.Results)...)
+		path = b.nextPath(list.Links.Next)
+	}
+	return labels, nil
+}
+
+func (b *v2Backend) findPageID(spaceKey, title string) (string, error) {
+	spaceID, err := b.spaceIDForKey(spaceKey)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("space-id", spaceID)
+	params.Set("title", title)
+
+	var list v2List[v2Page]
+	if err := b.getJSON("/api/v2/pages?"+params.Encode(), &list); err != nil {
+		return "", err
+	}
+	if len(list.Results) == 0 {
+		return "", fmt.Errorf("page %q not found in space %s", title, spaceKey)
+	}
+	return list.Results[0].ID, nil
+}
+
+func (b *v2Backend) createPage(spaceKey, parentID, title, storage string) (*Page, error) {
+	spaceID, err := b.spaceIDForKey(spaceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	req := v2PageRequest{
+		SpaceID:  spaceID,
+		Status:   "current",
+		Title:    title,
+		ParentID: parentID,
+		Body:     Storage{Value: storage, Representation: "storage"},
+	}
+	return b.writePage("POST", "/api/v2/pages", req)
+}
+
+func (b *v2Backend) updatePage(pageID, title, storage string, version int, message string) (*Page, error) {
+	req := v2PageRequest{
+		ID:      pageID,
+		Status:  "current",
+		Title:   title,
+		Body:    Storage{Value: storage, Representation: "storage"},
+		Version: &versionRef{Number: version, Message: message},
+	}
+	return b.writePage("PUT", "/api/v2/pages/"+pageID, req)
+}
+
+func (b *v2Backend) writePage(method, path string, req v2PageRequest) (*Page, error) {
+	resp, err := b.c.doRequestWithBody(method, path, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var p v2Page
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &Page{
+		ID:      p.ID,
+		Type:    "page",
+		Status:  p.Status,
+		Title:   p.Title,
+		Version: Version{Number: p.Version.Number, When: p.Version.CreatedAt, Message: p.Version.Message},
+		Links:   p.Links,
+	}, nil
+}
+
+// space resolves a space ID to its key and name.
+func (b *v2Backend) space(spaceID string) (Space, error) {
+	if spaceID == "" {
+		return Space{}, nil
+	}
+	if s, ok := b.spaces[spaceID]; ok {
+		return s, nil
+	}
+
+	var s v2Space
+	if err := b.getJSON("/api/v2/spaces/"+spaceID, &s); err != nil {
+		return Space{}, fmt.Errorf("looking up space %s: %w", spaceID, err)
+	}
+	b.spaces[spaceID] = Space{Key: s.Key, Name: s.Name}
+	return b.spaces[spaceID], nil
+}
+
+// author resolves the account ID v2 gives for a page or version author
+// to the user, whose display name v1 includes inline. A failed lookup
+// leaves just the account ID.
+func (b *v2Backend) author(accountID string) User {
+	if accountID == "" {
+		return User{}
+	}
+	u, err := b.c.GetUser(UserRef{AccountID: accountID})
+	if err != nil {
+		b.c.debugf("Resolving author %s: %v", accountID, err)
+		return User{AccountID: accountID}
+	}
+	return *u
+}
+
+func (b *v2Backend) spaceIDForKey(spaceKey string) (string, error) {
+	var list v2List[v2Space]
+	if err := b.getJSON("/api/v2/spaces?keys="+url.QueryEscape(spaceKey), &list); err != nil {
+		return "", fmt.Errorf("looking up space %s: %w", spaceKey, err)
+	}
+	if len(list.Results) == 0 {
+		return "", fmt.Errorf("space %s not found", spaceKey)
+	}
+	s := list.Results[0]
+	b.spaces[s.ID] = Space{Key: s.Key, Name: s.Name}
+	return s.ID, nil
+}
+
+func (b *v2Backend) getJSON(path string, v interface{}) error {
+	resp, err := b.c.doRequest("GET", path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// nextPath converts a v2 "_links.next" cursor link into a path relative to
+// the client's BaseURL. The link is relative to the site root, so it
+// repeats BaseURL's path prefix (e.g. "/wiki").
+func (b *v2Backend) nextPath(next string) string {
+	if next == "" {
+		return ""
+	}
+	base, err := url.Parse(b.c.BaseURL)
+	if err != nil {
+		return next
+	}
+	return strings.TrimPrefix(next, base.Path)
+}
@@ -0,0 +1,330 @@
+// Package diff computes line and word differences between two texts and
+// renders them as unified or word diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Delete
+	Insert
+)
+
+// Op is a single step of an edit script turning one sequence into another.
+type Op struct {
+	Kind OpKind
+	Text string
+}
+
+// Diff returns the shortest edit script from a to b using Myers'
+// algorithm in its linear-space form, which finds the middle of the edit
+// path and recurses on either side of it. Within each run of changes,
+// deletions come before insertions.
+func Diff(a, b []string) []Op {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return groupChanges(d.ops)
+}
+
+// differ accumulates the edit script for a and b.
+type differ struct {
+	a, b []string
+	ops  []Op
+}
+
+// compare appends the edit script from a[aLo:aHi] to b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, Op{Kind: Equal, Text: d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	x, y := -1, -1
+	if aLo < aHi && bLo < bHi {
+		x, y = d.middle(aLo, aHi, bLo, bHi)
+	}
+	if x < 0 {
+		for _, text := range d.a[aLo:aHi] {
+			d.ops = append(d.ops, Op{Kind: Delete, Text: text})
+		}
+		for _, text := range d.b[bLo:bHi] {
+			d.ops = append(d.ops, Op{Kind: Insert, Text: text})
+		}
+	} else {
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	}
+
+	for _, text := range d.a[aHi : aHi+suffix] {
+		d.ops = append(d.ops, Op{Kind: Equal, Text: text})
+	}
+}
+
+// middle finds where the forward and reverse searches for the shortest
+// edit path from a[aLo:aHi] to b[bLo:bHi] meet, and returns that point to
+// split the problem at. Both ranges must be non-empty and differ in their
+// first and last elements. It returns -1, -1 when the ranges have nothing
+// in common.
+func (d *differ) middle(aLo, aHi, bLo, bHi int) (int, int) {
+	a, b := d.a[aLo:aHi], d.b[bLo:bHi]
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[offset+k] is the furthest x reached on diagonal k = x-y from
+	// the start, and reverse[offset+k] the furthest from the end.
+	forward := make([]int, 2*maxD+2)
+	reverse := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], reverse[i] = -1, -1
+	}
+	forward[offset+1], reverse[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta the paths meet during a forward step, otherwise
+	// during a reverse one.
+	odd := delta%2 != 0
+	// Diagonals that have run off the edge of the grid are skipped.
+	fStart, fEnd, rStart, rEnd := 0, 0, 0, 0
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[i] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if j := offset + delta - k; j >= 0 && j < len(reverse) && reverse[j] != -1 && x >= n-reverse[j] {
+					return aLo + x, bLo + y
+				}
+			}
+		}
+
+		for k := -step + rStart; k <= step-rEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && reverse[i-1] < reverse[i+1]) {
+				x = reverse[i+1]
+			} else {
+				x = reverse[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			reverse[i] = x
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !odd:
+				if j := offset + delta - k; j >= 0 && j < len(forward) && forward[j] != -1 {
+					fx := forward[j]
+					if fx >= n-x {
+						return aLo + fx, bLo + fx - (j - offset)
+					}
+				}
+			}
+		}
+	}
+	return -1, -1
+}
+
+// groupChanges reorders each run of changes in ops so that its deletions
+// come before its insertions, as in a unified diff.
+func groupChanges(ops []Op) []Op {
+	grouped := make([]Op, 0, len(ops))
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == Equal {
+			grouped = append(grouped, ops[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(ops) && ops[end].Kind != Equal {
+			end++
+		}
+		for _, kind := range []OpKind{Delete, Insert} {
+			for _, op := range ops[i:end] {
+				if op.Kind == kind {
+					grouped = append(grouped, op)
+				}
+			}
+		}
+		i = end
+	}
+	return grouped
+}
+
+// Lines splits text into lines, ignoring a single trailing newline.
+func Lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Unified renders the line diff of a and b in unified format with the
+// given number of context lines. It returns an empty string when the
+// inputs are identical.
+func Unified(fromName, toName string, a, b []string, context int) string {
+	ops := Diff(a, b)
+
+	// aPos[i] and bPos[i] are the number of lines of a and b consumed
+	// before ops[i].
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	changed := false
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		switch op.Kind {
+		case Equal:
+			aPos[i+1]++
+			bPos[i+1]++
+		case Delete:
+			aPos[i+1]++
+			changed = true
+		case Insert:
+			bPos[i+1]++
+			changed = true
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	i := 0
+	for i < len(ops) {
+		for i < len(ops) && ops[i].Kind == Equal {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := max(i-context, 0)
+		end := i
+		for {
+			for end < len(ops) && ops[end].Kind != Equal {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].Kind == Equal {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end = min(end+context, len(ops))
+			break
+		}
+
+		aCount := aPos[end] - aPos[start]
+		bCount := bPos[end] - bPos[start]
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(aPos[start], aCount), hunkRange(bPos[start], bCount))
+		for _, op := range ops[start:end] {
+			switch op.Kind {
+			case Equal:
+				out.WriteString(" ")
+			case Delete:
+				out.WriteString("-")
+			case Insert:
+				out.WriteString("+")
+			}
+			out.WriteString(op.Text)
+			out.WriteString("\n")
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// Words renders a word-level diff of a and b, marking removed text as
+// [-text-] and added text as {+text+}, in the style of git's
+// --word-diff=plain.
+func Words(a, b string) string {
+	ops := Diff(tokenize(a), tokenize(b))
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		kind := ops[i].Kind
+		var run strings.Builder
+		for i < len(ops) && ops[i].Kind == kind {
+			run.WriteString(ops[i].Text)
+			i++
+		}
+		switch kind {
+		case Equal:
+			out.WriteString(run.String())
+		case Delete:
+			fmt.Fprintf(&out, "[-%s-]", run.String())
+		case Insert:
+			fmt.Fprintf(&out, "{+%s+}", run.String())
+		}
+	}
+	return out.String()
+}
+
+// tokenize splits text into alternating runs of whitespace and
+// non-whitespace so that joining the tokens reproduces the input.
+func tokenize(text string) []string {
+	var tokens []string
+	start := 0
+	inSpace := false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if i > 0 && space != inSpace {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
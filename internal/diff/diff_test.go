@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want string
+	}{
+		{
+			name: "identical",
+			a:    []string{"a", "b"},
+			b:    []string{"a", "b"},
+			want: "=a =b",
+		},
+		{
+			name: "both empty",
+			want: "",
+		},
+		{
+			name: "insert into empty",
+			b:    []string{"a"},
+			want: "+a",
+		},
+		{
+			name: "replace middle line",
+			a:    []string{"a", "b", "c"},
+			b:    []string{"a", "x", "c"},
+			want: "=a -b +x =c",
+		},
+		{
+			name: "delete at end",
+			a:    []string{"a", "b"},
+			b:    []string{"a"},
+			want: "=a -b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parts []string
+			for _, op := range Diff(tt.a, tt.b) {
+				prefix := map[OpKind]string{Equal: "=", Delete: "-", Insert: "+"}[op.Kind]
+				parts = append(parts, prefix+op.Text)
+			}
+			if got := strings.Join(parts, " "); got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := Lines("one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n")
+	b := Lines("one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n")
+
+	got := Unified("v1", "v2", a, b, 1)
+	want := `--- v1
++++ v2
+@@ -2,3 +2,3 @@
+ two
+-three
++THREE
+ four
+@@ -10 +10,2 @@
+ ten
++eleven
+`
+	if got != want {
+		t.Errorf("Unified() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_NoChanges(t *testing.T) {
+	lines := Lines("same\ntext\n")
+	if got := Unified("a", "b", lines, lines, 3); got != "" {
+		t.Errorf("expected empty diff for identical input, got: %s", got)
+	}
+}
+
+func TestWords(t *testing.T) {
+	got := Words("the quick brown fox", "the slow brown dog")
+	want := "the [-quick-]{+slow+} brown [-fox-]{+dog+}"
+	if got != want {
+		t.Errorf("Words() = %q, want %q", got, want)
+	}
+}
+
+func TestDiff_Minimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		s := make([]string, rng.Intn(12))
+		for i := range s {
+			s[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return s
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := random(), random()
+		ops := Diff(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, op := range ops {
+			if op.Kind != Insert {
+				gotA = append(gotA, op.Text)
+			}
+			if op.Kind != Delete {
+				gotB = append(gotB, op.Text)
+			}
+			if op.Kind != Equal {
+				edits++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+			t.Fatalf("Diff(%v, %v) = %v doesn't turn one into the other", a, b, ops)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("Diff(%v, %v) made %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestWords_LargeRewrite(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&a, "old%d ", i)
+		fmt.Fprintf(&b, "new%d ", i)
+	}
+	got := Words(a.String(), b.String())
+	if !strings.HasPrefix(got, "[-old0-]{+new0+} [-old1-]{+new1+}") {
+		t.Errorf("unexpected word diff: %.100s", got)
+	}
+}
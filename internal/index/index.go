@@ -0,0 +1,283 @@
+// Package index builds a full-text index over exported Markdown files and
+// answers ranked queries from it, without contacting Confluence.
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/justinabrahms/confluence-md/internal/markdown"
+)
+
+// FileName is the name of the index file written to the indexed directory.
+const FileName = ".confluence-md-index.json"
+
+// formatVersion changes whenever the index layout or tokenization does,
+// so older index files are rebuilt instead of misread.
+const formatVersion = 1
+
+// Doc is an indexed Markdown file.
+type Doc struct {
+	// Path is the file's path relative to the indexed directory, with
+	// slashes.
+	Path    string    `json:"path"`
+	Title   string    `json:"title"`
+	PageID  string    `json:"page_id,omitempty"`
+	Space   string    `json:"space,omitempty"`
+	URL     string    `json:"url,omitempty"`
+	Updated time.Time `json:"updated"`
+	// Modified is the file's modification time when it was indexed.
+	Modified time.Time `json:"modified"`
+	// Length is the number of indexed terms.
+	Length int `json:"length"`
+	// Text is the Markdown without front matter, link targets or HTML
+	// tags, kept for snippets.
+	Text string `json:"text"`
+}
+
+// Posting records where a term occurs in a document.
+type Posting struct {
+	Doc       int   `json:"d"`
+	Positions []int `json:"p"`
+}
+
+// Index is an inverted index over the Markdown files in a directory.
+type Index struct {
+	Version int                  `json:"version"`
+	Docs    []Doc                `json:"docs"`
+	Terms   map[string][]Posting `json:"terms"`
+}
+
+// token is a term in a text, with the byte offsets of the word it came
+// from.
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into lower-cased, stemmed words.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text + " " {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{term: stem(strings.ToLower(text[start:i])), start: start, end: i})
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// Build indexes every Markdown file below dir, skipping hidden files and
+// directories.
+func Build(dir string) (*Index, error) {
+	ix := &Index{Version: formatVersion, Terms: map[string][]Posting{}}
+	err := walkMarkdown(dir, func(path string, info fs.FileInfo) error {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		ix.add(newDoc(filepath.ToSlash(rel), info.ModTime(), src))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// newDoc describes a Markdown file from its front matter, falling back to
+// its first heading or file name for the title and its modification time
+// for the update date.
+func newDoc(path string, modified time.Time, src []byte) Doc {
+	doc := Doc{Path: path, Modified: modified, Updated: modified}
+	fm, body, err := markdown.SplitFrontMatter(src)
+	if err != nil {
+		fm, body = nil, src
+	}
+	doc.Text = plainText(string(body))
+
+	if fm != nil {
+		doc.Title, doc.PageID, doc.Space, doc.URL = fm.Title, fm.ID, fm.Space, fm.URL
+		dates := []string{fm.LastMod, fm.Date}
+		if fm.LastUpdate != nil {
+			dates = append([]string{fm.LastUpdate.Date}, dates...)
+		}
+		for _, d := range dates {
+			if t, err := time.Parse(time.RFC3339, d); err == nil {
+				doc.Updated = t
+				break
+			}
+		}
+	}
+	if doc.Title == "" {
+		if sections := markdown.Sections(doc.Text); len(sections) > 0 && sections[0].Level == 1 {
+			doc.Title = sections[0].Title
+		} else {
+			doc.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+	}
+	return doc
+}
+
+var (
+	markdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	htmlTag      = regexp.MustCompile(`<[^>]+>`)
+)
+
+// plainText reduces links and images in md to their text and drops HTML
+// tags, so URLs and markup neither match queries nor fill snippets.
+func plainText(md string) string {
+	return htmlTag.ReplaceAllString(markdownLink.ReplaceAllString(md, "$1"), " ")
+}
+
+// add indexes doc's title and text. A gap between them keeps phrases from
+// matching across the two.
+func (ix *Index) add(doc Doc) {
+	id := len(ix.Docs)
+	postings := map[string]*Posting{}
+	pos := 0
+	for _, text := range []string{doc.Title, doc.Text} {
+		for _, tok := range tokenize(text) {
+			p, ok := postings[tok.term]
+			if !ok {
+				p = &Posting{Doc: id}
+				postings[tok.term] = p
+			}
+			p.Positions = append(p.Positions, pos)
+			pos++
+			doc.Length++
+		}
+		pos++
+	}
+	for term, p := range postings {
+		ix.Terms[term] = append(ix.Terms[term], *p)
+	}
+	ix.Docs = append(ix.Docs, doc)
+}
+
+// Save writes the index to FileName in dir.
+func (ix *Index) Save(dir string) error {
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return fmt.Errorf("encoding index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), data, 0644); err != nil {
+		return fmt.Errorf("writing index: %w", err)
+	}
+	return nil
+}
+
+// Load reads the index saved in dir.
+func Load(dir string) (*Index, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, fmt.Errorf("reading index: %w", err)
+	}
+	var ix Index
+	if err := json.Unmarshal(data, &ix); err != nil {
+		return nil, fmt.Errorf("parsing index: %w", err)
+	}
+	if ix.Terms == nil {
+		ix.Terms = map[string][]Posting{}
+	}
+	return &ix, nil
+}
+
+// Open loads the index saved in dir, or builds a new one when it is
+// missing, from an older version, or out of date with the Markdown files.
+// rebuilt reports whether it did, so the caller can save the new index.
+func Open(dir string) (ix *Index, rebuilt bool, err error) {
+	ix, err = Load(dir)
+	if err == nil && ix.Version == formatVersion {
+		current, err := ix.current(dir)
+		if err != nil {
+			return nil, false, err
+		}
+		if current {
+			return ix, false, nil
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+
+	ix, err = Build(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	return ix, true, nil
+}
+
+// current reports whether the index covers exactly the Markdown files in
+// dir, as last modified.
+func (ix *Index) current(dir string) (bool, error) {
+	modified := map[string]time.Time{}
+	for _, doc := range ix.Docs {
+		modified[doc.Path] = doc.Modified
+	}
+	seen := 0
+	stale := errors.New("stale")
+	err := walkMarkdown(dir, func(path string, info fs.FileInfo) error {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if m, ok := modified[filepath.ToSlash(rel)]; !ok || !m.Equal(info.ModTime()) {
+			return stale
+		}
+		seen++
+		return nil
+	})
+	if errors.Is(err, stale) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return seen == len(ix.Docs), nil
+}
+
+// walkMarkdown calls fn for each .md and .mdx file below dir, skipping
+// hidden files and directories such as .git and .obsidian.
+func walkMarkdown(dir string, fn func(path string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ext := strings.ToLower(filepath.Ext(path)); ext != ".md" && ext != ".mdx" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(path, info)
+	})
+}
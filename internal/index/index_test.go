@@ -0,0 +1,120 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestSearch(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Runbook.md":         "---\nid: \"1\"\ntitle: Runbook\nspace: OPS\nversion: 3\nurl: https://example.com/pages/1\n---\n\n# Runbook\n\nHow to roll back a failed deployment.\n",
+		"Runbook/Deploys.md": "# Deploys\n\nWe deploy twice a day. Deploying on Fridays needs approval. Back up and roll the dice.\n",
+		"Onboarding.md":      "# Onboarding\n\nWelcome! Set up your laptop.\n",
+		".obsidian/notes.md": "deploy deploy deploy",
+		"notes.txt":          "deploy",
+	})
+	ix, err := Build(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ix.Docs) != 3 {
+		t.Fatalf("expected 3 documents, got %d", len(ix.Docs))
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"stemmed term", "deployed", []string{"Runbook/Deploys.md"}},
+		{"any term", "welcome approval", []string{"Onboarding.md", "Runbook/Deploys.md"}},
+		{"phrase", `"roll back"`, []string{"Runbook.md"}},
+		{"phrase and term", `"roll back" laptop`, []string{"Runbook.md"}},
+		{"title", "onboarding", []string{"Onboarding.md"}},
+		{"no match", "kubernetes", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range ix.Search(ParseQuery(tt.query)) {
+				got = append(got, r.Doc.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	doc := ix.Search(ParseQuery("runbook"))[0].Doc
+	if doc.Title != "Runbook" || doc.Space != "OPS" || doc.PageID != "1" || doc.URL != "https://example.com/pages/1" {
+		t.Errorf("expected front matter fields, got %+v", doc)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	doc := &Doc{Text: "# Runbook\n\nSome intro text that goes on for a while before the interesting part starts. " +
+		"To roll back, run the deploy job with the previous tag.\n\nMore text follows here at the end."}
+
+	got := ParseQuery(`"roll back" deploy`).Snippet(doc)
+	want := "...before the interesting part starts. To **roll back**, run the **deploy** job with the previous tag. More text follows here at the end."
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	input := "See [the runbook](https://example.com/runbook) and ![diagram](attachments/flow.png).<br/>Done"
+	want := "See the runbook and diagram. Done"
+	if got := plainText(input); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.md": "# A\n\nalpha\n"})
+
+	ix, rebuilt, err := Open(dir)
+	if err != nil || !rebuilt {
+		t.Fatalf("expected a new index, got rebuilt=%v, %v", rebuilt, err)
+	}
+	if err := ix.Save(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, rebuilt, err := Open(dir); err != nil || rebuilt {
+		t.Fatalf("expected the saved index, got rebuilt=%v, %v", rebuilt, err)
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "a.md"), later, later); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, rebuilt, err := Open(dir); err != nil || !rebuilt {
+		t.Errorf("expected a changed file to rebuild the index, got rebuilt=%v, %v", rebuilt, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "b.md"), []byte("beta"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ix, rebuilt, err = Open(dir)
+	if err != nil || !rebuilt || len(ix.Docs) != 2 {
+		t.Errorf("expected a new file to rebuild the index, got rebuilt=%v, %d docs, %v", rebuilt, len(ix.Docs), err)
+	}
+}
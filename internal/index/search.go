@@ -0,0 +1,235 @@
+package index
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BM25 parameters: k1 limits how much repeating a term raises a score, and
+// b how much document length is normalized.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Snippet size, in words: the snippet window, and how many words before
+// the first match it starts.
+const (
+	snippetWords   = 30
+	snippetContext = 6
+)
+
+// Query is a parsed search query: words, any of which may match, and
+// quoted phrases, all of which must.
+type Query struct {
+	Terms   []string
+	Phrases [][]string
+}
+
+var quotedPhrase = regexp.MustCompile(`"([^"]*)"`)
+
+// ParseQuery parses a query like `deploy "roll back"`.
+func ParseQuery(s string) Query {
+	var q Query
+	seen := map[string]bool{}
+	rest := quotedPhrase.ReplaceAllStringFunc(s, func(m string) string {
+		var phrase []string
+		for _, tok := range tokenize(m) {
+			phrase = append(phrase, tok.term)
+		}
+		if len(phrase) > 0 {
+			q.Phrases = append(q.Phrases, phrase)
+		}
+		return " "
+	})
+	for _, tok := range tokenize(rest) {
+		if !seen[tok.term] {
+			seen[tok.term] = true
+			q.Terms = append(q.Terms, tok.term)
+		}
+	}
+	return q
+}
+
+// Result is a document matching a query.
+type Result struct {
+	Doc   *Doc
+	Score float64
+}
+
+// Search ranks the documents matching q by BM25, best first. A phrase
+// counts as a single term occurring wherever the whole phrase does.
+func (ix *Index) Search(q Query) []Result {
+	if len(ix.Docs) == 0 {
+		return nil
+	}
+	total := 0
+	for _, doc := range ix.Docs {
+		total += doc.Length
+	}
+	avgLength := float64(total) / float64(len(ix.Docs))
+
+	scores := map[int]float64{}
+	score := func(matches map[int]int) {
+		idf := math.Log(1 + (float64(len(ix.Docs))-float64(len(matches))+0.5)/(float64(len(matches))+0.5))
+		for id, tf := range matches {
+			norm := 1 - bm25B + bm25B*float64(ix.Docs[id].Length)/avgLength
+			scores[id] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+		}
+	}
+
+	var required map[int]bool
+	for _, phrase := range q.Phrases {
+		matches := ix.phraseMatches(phrase)
+		score(matches)
+		if required == nil {
+			required = map[int]bool{}
+			for id := range matches {
+				required[id] = true
+			}
+			continue
+		}
+		for id := range required {
+			if matches[id] == 0 {
+				delete(required, id)
+			}
+		}
+	}
+	for _, term := range q.Terms {
+		matches := map[int]int{}
+		for _, p := range ix.Terms[term] {
+			matches[p.Doc] = len(p.Positions)
+		}
+		score(matches)
+	}
+
+	var results []Result
+	for id, s := range scores {
+		if required != nil && !required[id] {
+			continue
+		}
+		results = append(results, Result{Doc: &ix.Docs[id], Score: s})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Doc.Path < results[j].Doc.Path
+	})
+	return results
+}
+
+// phraseMatches counts the occurrences of phrase in each document that
+// contains it.
+func (ix *Index) phraseMatches(phrase []string) map[int]int {
+	positions := make([]map[int][]int, len(phrase))
+	for i, term := range phrase {
+		positions[i] = map[int][]int{}
+		for _, p := range ix.Terms[term] {
+			positions[i][p.Doc] = p.Positions
+		}
+	}
+
+	matches := map[int]int{}
+	for id, starts := range positions[0] {
+	next:
+		for _, start := range starts {
+			for i := 1; i < len(phrase); i++ {
+				pos := positions[i][id]
+				at := sort.SearchInts(pos, start+i)
+				if at == len(pos) || pos[at] != start+i {
+					continue next
+				}
+			}
+			matches[id]++
+		}
+	}
+	return matches
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// Snippet returns the part of doc's text where q's terms are densest, with
+// matching words in bold.
+func (q Query) Snippet(doc *Doc) string {
+	tokens := tokenize(doc.Text)
+	if len(tokens) == 0 {
+		return ""
+	}
+	hit := q.highlights(tokens)
+
+	// Start at the window holding the most distinct matching terms
+	start, best := 0, 0
+	for i := range tokens {
+		if !hit[i] {
+			continue
+		}
+		from := max(i-snippetContext, 0)
+		distinct := map[string]bool{}
+		for j := from; j < min(from+snippetWords, len(tokens)); j++ {
+			if hit[j] {
+				distinct[tokens[j].term] = true
+			}
+		}
+		if len(distinct) > best {
+			start, best = from, len(distinct)
+		}
+	}
+	end := min(start+snippetWords, len(tokens))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	for i := start; i < end; i++ {
+		joined := i > start && hit[i-1] && strings.TrimSpace(doc.Text[tokens[i-1].end:tokens[i].start]) == ""
+		if i > start {
+			b.WriteString(whitespace.ReplaceAllString(doc.Text[tokens[i-1].end:tokens[i].start], " "))
+		}
+		if hit[i] && !joined {
+			b.WriteString("**")
+		}
+		b.WriteString(doc.Text[tokens[i].start:tokens[i].end])
+		closes := i+1 == end || !hit[i+1] || strings.TrimSpace(doc.Text[tokens[i].end:tokens[i+1].start]) != ""
+		if hit[i] && closes {
+			b.WriteString("**")
+		}
+	}
+	if end < len(tokens) {
+		b.WriteString("...")
+	} else {
+		b.WriteString(whitespace.ReplaceAllString(doc.Text[tokens[end-1].end:], " "))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// highlights marks the tokens that match one of q's terms or are part of
+// one of its phrases.
+func (q Query) highlights(tokens []token) []bool {
+	hit := make([]bool, len(tokens))
+	terms := map[string]bool{}
+	for _, term := range q.Terms {
+		terms[term] = true
+	}
+	for i, tok := range tokens {
+		if terms[tok.term] {
+			hit[i] = true
+		}
+	}
+	for _, phrase := range q.Phrases {
+	next:
+		for i := 0; i+len(phrase) <= len(tokens); i++ {
+			for j, term := range phrase {
+				if tokens[i+j].term != term {
+					continue next
+				}
+			}
+			for j := range phrase {
+				hit[i+j] = true
+			}
+		}
+	}
+	return hit
+}
@@ -0,0 +1,266 @@
+package index
+
+// stem reduces an English word to its stem with Porter's algorithm, so
+// that "deploying", "deployed" and "deploys" all index alike. Words that
+// aren't lower-case ASCII letters are returned unchanged.
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	z := &stemmer{b: []byte(word), k: len(word) - 1}
+	z.step1ab()
+	if z.k > 0 {
+		z.step1c()
+		z.step2()
+		z.step3()
+		z.step4()
+		z.step5()
+	}
+	return string(z.b[:z.k+1])
+}
+
+// stemmer holds a word being stemmed: b[:k+1] is the current word, and j
+// marks the end of the stem once ends has matched a suffix.
+type stemmer struct {
+	b    []byte
+	k, j int
+}
+
+// cons reports whether b[i] is a consonant.
+func (z *stemmer) cons(i int) bool {
+	switch z.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !z.cons(i-1)
+	}
+	return true
+}
+
+// m counts the vowel-consonant sequences in b[:j+1], the stem's "measure".
+func (z *stemmer) m() int {
+	n, i := 0, 0
+	for ; ; i++ {
+		if i > z.j {
+			return n
+		}
+		if !z.cons(i) {
+			break
+		}
+	}
+	for i++; ; i++ {
+		for ; ; i++ {
+			if i > z.j {
+				return n
+			}
+			if z.cons(i) {
+				break
+			}
+		}
+		n++
+		for i++; ; i++ {
+			if i > z.j {
+				return n
+			}
+			if !z.cons(i) {
+				break
+			}
+		}
+	}
+}
+
+// vowelInStem reports whether b[:j+1] contains a vowel.
+func (z *stemmer) vowelInStem() bool {
+	for i := 0; i <= z.j; i++ {
+		if !z.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleCons reports whether b[i-1:i+1] is a double consonant.
+func (z *stemmer) doubleCons(i int) bool {
+	return i >= 1 && z.b[i] == z.b[i-1] && z.cons(i)
+}
+
+// cvc reports whether b[i-2:i+1] is consonant-vowel-consonant with the
+// last consonant not w, x or y, as in "hop" but not "snow".
+func (z *stemmer) cvc(i int) bool {
+	if i < 2 || !z.cons(i) || z.cons(i-1) || !z.cons(i-2) {
+		return false
+	}
+	switch z.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends reports whether the word ends with s, setting j to the end of the
+// stem before it.
+func (z *stemmer) ends(s string) bool {
+	if len(s) > z.k+1 || string(z.b[z.k+1-len(s):z.k+1]) != s {
+		return false
+	}
+	z.j = z.k - len(s)
+	return true
+}
+
+// setTo replaces the suffix after j with s.
+func (z *stemmer) setTo(s string) {
+	z.b = append(z.b[:z.j+1], s...)
+	z.k = z.j + len(s)
+}
+
+// replace replaces the suffix after j with s if the stem's measure is
+// positive.
+func (z *stemmer) replace(s string) {
+	if z.m() > 0 {
+		z.setTo(s)
+	}
+}
+
+// step1ab removes plurals and -ed or -ing.
+func (z *stemmer) step1ab() {
+	if z.b[z.k] == 's' {
+		switch {
+		case z.ends("sses"):
+			z.k -= 2
+		case z.ends("ies"):
+			z.setTo("i")
+		case z.b[z.k-1] != 's':
+			z.k--
+		}
+	}
+	if z.ends("eed") {
+		if z.m() > 0 {
+			z.k--
+		}
+		return
+	}
+	if (z.ends("ed") || z.ends("ing")) && z.vowelInStem() {
+		z.k = z.j
+		switch {
+		case z.ends("at"):
+			z.setTo("ate")
+		case z.ends("bl"):
+			z.setTo("ble")
+		case z.ends("iz"):
+			z.setTo("ize")
+		case z.doubleCons(z.k):
+			switch z.b[z.k] {
+			case 'l', 's', 'z':
+			default:
+				z.k--
+			}
+		default:
+			z.j = z.k
+			if z.m() == 1 && z.cvc(z.k) {
+				z.setTo("e")
+			}
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there is another vowel in the stem.
+func (z *stemmer) step1c() {
+	if z.ends("y") && z.vowelInStem() {
+		z.b[z.k] = 'i'
+	}
+}
+
+// step2Suffixes are the suffixes step2 replaces, by their second to last
+// letter.
+var step2Suffixes = map[byte][][2]string{
+	'a': {{"ational", "ate"}, {"tional", "tion"}},
+	'c': {{"enci", "ence"}, {"anci", "ance"}},
+	'e': {{"izer", "ize"}},
+	'l': {{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}},
+	'o': {{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}},
+	's': {{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}},
+	't': {{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}},
+	'g': {{"logi", "log"}},
+}
+
+// step2 maps double suffixes to single ones: -ization becomes -ize.
+func (z *stemmer) step2() {
+	z.replaceSuffix(step2Suffixes[z.b[z.k-1]])
+}
+
+// step3Suffixes are the suffixes step3 replaces, by their last letter.
+var step3Suffixes = map[byte][][2]string{
+	'e': {{"icate", "ic"}, {"ative", ""}, {"alize", "al"}},
+	'i': {{"iciti", "ic"}},
+	'l': {{"ical", "ic"}, {"ful", ""}},
+	's': {{"ness", ""}},
+}
+
+// step3 handles -ic-, -full, -ness and the like.
+func (z *stemmer) step3() {
+	z.replaceSuffix(step3Suffixes[z.b[z.k]])
+}
+
+// replaceSuffix replaces the first of the suffixes the word ends with.
+func (z *stemmer) replaceSuffix(suffixes [][2]string) {
+	for _, s := range suffixes {
+		if z.ends(s[0]) {
+			z.replace(s[1])
+			return
+		}
+	}
+}
+
+// step4Suffixes are the suffixes step4 removes, by their second to last
+// letter. Suffixes ending in -ion and -ou are handled separately.
+var step4Suffixes = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+// step4 removes -ant, -ence and the like from stems of measure above 1.
+func (z *stemmer) step4() {
+	matched := false
+	if z.b[z.k-1] == 'o' {
+		matched = z.ends("ion") && z.j >= 0 && (z.b[z.j] == 's' || z.b[z.j] == 't') || z.ends("ou")
+	} else {
+		for _, s := range step4Suffixes[z.b[z.k-1]] {
+			if z.ends(s) {
+				matched = true
+				break
+			}
+		}
+	}
+	if matched && z.m() > 1 {
+		z.k = z.j
+	}
+}
+
+// step5 removes a final -e and reduces -ll to -l in long stems.
+func (z *stemmer) step5() {
+	z.j = z.k
+	if z.b[z.k] == 'e' {
+		a := z.m()
+		if a > 1 || a == 1 && !z.cvc(z.k-1) {
+			z.k--
+		}
+	}
+	if z.b[z.k] == 'l' && z.doubleCons(z.k) && z.m() > 1 {
+		z.k--
+	}
+}
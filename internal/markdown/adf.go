@@ -0,0 +1,544 @@
+package markdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// adfNode is a node in an Atlassian Document Format (ADF) document.
+type adfNode struct {
+	Type    string                 `json:"type"`
+	Attrs   map[string]interface{} `json:"attrs"`
+	Content []adfNode              `json:"content"`
+	Text    string                 `json:"text"`
+	Marks   []adfMark              `json:"marks"`
+}
+
+type adfMark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs"`
+}
+
+// panelAlerts maps Confluence panel types (ADF panelType values and panel
+// macro names) to GFM alert types. It is the inverse of alertMacros, so
+// panels survive a fetch/publish round trip.
+var panelAlerts = map[string]string{
+	"info":    "NOTE",
+	"note":    "IMPORTANT",
+	"tip":     "TIP",
+	"success": "TIP",
+	"warning": "WARNING",
+	"error":   "CAUTION",
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+)
+
+// ADFToMarkdown renders an Atlassian Document Format JSON document as
+// GitHub Flavored Markdown without going through HTML.
+func ADFToMarkdown(doc string) (string, error) {
+	return NewConverter().adfToMarkdown(doc, renderContext{})
+}
+
+// adfRenderer renders ADF nodes in its converter's flavor.
+type adfRenderer struct {
+	c   *Converter
+	ctx renderContext
+}
+
+// adfToMarkdown renders an ADF JSON document in the converter's flavor.
+func (c *Converter) adfToMarkdown(doc string, ctx renderContext) (string, error) {
+	var root adfNode
+	if err := json.Unmarshal([]byte(doc), &root); err != nil {
+		return "", fmt.Errorf("parsing ADF document: %w", err)
+	}
+	if root.Type != "doc" {
+		return "", fmt.Errorf("parsing ADF document: unexpected root node %q", root.Type)
+	}
+
+	ctx.headings = adfHeadings(root.Content)
+	r := &adfRenderer{c: c, ctx: ctx}
+	return r.blocks(root.Content, "\n\n"), nil
+}
+
+// adfHeadings lists the headings among nodes and their descendants, for
+// tables of contents.
+func adfHeadings(nodes []adfNode) []heading {
+	var headings []heading
+	for _, n := range nodes {
+		if n.Type == "heading" {
+			if text := strings.Join(strings.Fields(adfText(n.Content)), " "); text != "" {
+				headings = append(headings, heading{level: max(1, min(6, adfInt(n.Attrs, "level", 1))), text: text})
+			}
+			continue
+		}
+		headings = append(headings, adfHeadings(n.Content)...)
+	}
+	return headings
+}
+
+// adfText returns the text content of nodes.
+func adfText(nodes []adfNode) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(n.Text)
+		b.WriteString(adfText(n.Content))
+	}
+	return b.String()
+}
+
+// blocks renders block nodes and joins them with sep.
+func (r *adfRenderer) blocks(nodes []adfNode, sep string) string {
+	var blocks []string
+	for _, n := range nodes {
+		if block := r.block(n); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return strings.Join(blocks, sep)
+}
+
+func (r *adfRenderer) block(n adfNode) string {
+	f := r.c.flavor
+	bullet := f.listMarker(f.BulletMarker + " ")
+	switch n.Type {
+	case "paragraph":
+		return r.inline(n.Content)
+
+	case "heading":
+		level := max(1, min(6, adfInt(n.Attrs, "level", 1)))
+		return f.heading(level, r.inline(n.Content))
+
+	case "bulletList":
+		var items []string
+		for _, item := range n.Content {
+			items = append(items, listItem(bullet, r.blocks(item.Content, "\n")))
+		}
+		return strings.Join(items, "\n")
+
+	case "orderedList":
+		start := adfInt(n.Attrs, "order", 1)
+		var items []string
+		for i, item := range n.Content {
+			items = append(items, listItem(f.listMarker(fmt.Sprintf("%d. ", start+i)), r.blocks(item.Content, "\n")))
+		}
+		return strings.Join(items, "\n")
+
+	case "taskList":
+		var items []string
+		for _, item := range n.Content {
+			if item.Type == "taskList" {
+				items = append(items, indent(r.block(item), strings.Repeat(" ", len(bullet))))
+				continue
+			}
+			box := "[ ] "
+			if adfString(item.Attrs, "state") == "DONE" {
+				box = "[x] "
+			}
+			items = append(items, listItem(bullet+box, r.inline(item.Content)))
+		}
+		return strings.Join(items, "\n")
+
+	case "decisionList":
+		var items []string
+		for _, item := range n.Content {
+			items = append(items, listItem(bullet, "Decision: "+r.inline(item.Content)))
+		}
+		return strings.Join(items, "\n")
+
+	case "codeBlock":
+		var code strings.Builder
+		for _, c := range n.Content {
+			code.WriteString(c.Text)
+		}
+		return r.c.fencedCode(adfString(n.Attrs, "language"), code.String())
+
+	case "blockquote":
+		return quote(r.blocks(n.Content, "\n\n"))
+
+	case "rule":
+		return "---"
+
+	case "panel":
+		alert, ok := panelAlerts[adfString(n.Attrs, "panelType")]
+		if !ok {
+			alert = "NOTE"
+		}
+		return r.c.admonition(alert, "", r.blocks(n.Content, "\n\n"))
+
+	case "expand", "nestedExpand":
+		return "<details>\n<summary>" + html.EscapeString(adfString(n.Attrs, "title")) + "</summary>\n\n" +
+			r.blocks(n.Content, "\n\n") + "\n\n</details>"
+
+	case "mediaSingle", "mediaGroup":
+		var media []string
+		for _, m := range n.Content {
+			if m.Type == "media" {
+				media = append(media, r.media(m))
+			}
+		}
+		return strings.Join(media, "\n")
+
+	case "table":
+		return r.table(n)
+
+	case "blockCard", "embedCard":
+		href := adfString(n.Attrs, "url")
+		return r.link(href, href)
+
+	case "layoutSection", "layoutColumn":
+		return r.blocks(n.Content, "\n\n")
+
+	case "extension":
+		return r.renderMacro(r.extensionMacro(n, false))
+
+	case "bodiedExtension":
+		// Macro handlers read storage format bodies, which an ADF body
+		// isn't, so these only follow the unknown-macro policy
+		switch r.c.unknownMacros {
+		case UnknownMacroDrop:
+			return ""
+		case UnknownMacroComment:
+			return macroComment(r.extensionMacro(n, false))
+		}
+		return r.blocks(n.Content, "\n\n")
+
+	default:
+		if len(n.Content) > 0 {
+			return r.blocks(n.Content, "\n\n")
+		}
+		return r.inline([]adfNode{n})
+	}
+}
+
+// extensionMacro describes an extension node, which is how ADF stores
+// macros, as the structured macro it stands for.
+func (r *adfRenderer) extensionMacro(n adfNode, inline bool) *Macro {
+	params := map[string]string{}
+	if p, ok := n.Attrs["parameters"].(map[string]interface{}); ok {
+		if macroParams, ok := p["macroParams"].(map[string]interface{}); ok {
+			for name, v := range macroParams {
+				if v, ok := v.(map[string]interface{}); ok {
+					params[name] = adfString(v, "value")
+				}
+			}
+		}
+	}
+	return r.newMacro(adfString(n.Attrs, "extensionKey"), params, inline)
+}
+
+// newMacro builds the structured macro element for a macro found in ADF,
+// so it renders through the same handlers as in storage format.
+func (r *adfRenderer) newMacro(name string, params map[string]string, inline bool) *Macro {
+	node := storage.NewElement("ac:structured-macro", storage.Attr{Name: "ac:name", Value: name})
+	names := make([]string, 0, len(params))
+	for k := range params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		p := storage.NewElement("ac:parameter", storage.Attr{Name: "ac:name", Value: k})
+		p.AppendChild(storage.NewText(params[k]))
+		node.AppendChild(p)
+	}
+	return r.c.newMacro(node, inline, r.ctx)
+}
+
+// renderMacro renders a macro with its handler, or by the unknown-macro
+// policy. ADF rendering can't fail, so errors are left as a comment.
+func (r *adfRenderer) renderMacro(m *Macro) string {
+	md, err := r.c.renderMacro(m)
+	if err != nil {
+		return includeComment(fmt.Sprintf("rendering %s macro: %v", m.Name, err))
+	}
+	return md
+}
+
+// mention renders a user mention like storage format's: the mention's own
+// text, or the user's name from the user resolver, linked to the user's
+// profile when enabled.
+func (r *adfRenderer) mention(n adfNode) string {
+	ref := confluence.UserRef{AccountID: adfString(n.Attrs, "id")}
+	name, profile := ref.String(), ""
+	if r.c.users != nil {
+		if user, err := r.c.users.GetUser(ref); err == nil {
+			name, profile = user.Name(), user.ProfileURL
+		}
+	}
+	if text := strings.TrimPrefix(strings.TrimSpace(adfString(n.Attrs, "text")), "@"); text != "" {
+		name = text
+	}
+
+	if !r.c.userLinks || profile == "" {
+		return "@" + name
+	}
+	return "[@" + markdownEscaper.Replace(name) + "](" + markdownLinkDestination(profile) + ")"
+}
+
+// media renders a media node as an image. External media keep their URL;
+// attached files go through the attachment resolver by file name, like
+// images in storage format. Confluence puts the file name in __fileName,
+// and usually in alt too.
+func (r *adfRenderer) media(m adfNode) string {
+	alt := adfString(m.Attrs, "alt")
+	if adfString(m.Attrs, "type") == "external" {
+		return "![" + markdownEscaper.Replace(alt) + "](" + markdownLinkDestination(adfString(m.Attrs, "url")) + ")"
+	}
+	filename := adfString(m.Attrs, "__fileName")
+	if filename == "" {
+		filename = alt
+	}
+	if filename == "" {
+		return includeComment("attachment " + adfString(m.Attrs, "id") + " has no file name")
+	}
+	return r.c.attachmentEmbed(r.ctx, filename, alt)
+}
+
+// adfInlineBlocks are the ADF block nodes a pipe table cell can hold,
+// with paragraphs joined by <br>.
+var adfInlineBlocks = map[string]bool{"paragraph": true, "mediaSingle": true, "mediaGroup": true}
+
+// table renders a table like a storage-format one: as a pipe table when it
+// fits, otherwise in the converter's table style.
+func (r *adfRenderer) table(n adfNode) string {
+	var rows [][]*tableCell
+	for _, row := range n.Content {
+		var cells []*tableCell
+		for _, cell := range row.Content {
+			tc := &tableCell{
+				header:   cell.Type == "tableHeader",
+				rowspan:  max(1, adfInt(cell.Attrs, "rowspan", 1)),
+				colspan:  max(1, adfInt(cell.Attrs, "colspan", 1)),
+				markdown: r.blocks(cell.Content, "\n\n"),
+				rendered: true,
+			}
+			for _, c := range cell.Content {
+				if !adfInlineBlocks[c.Type] {
+					tc.block = true
+				}
+			}
+			cells = append(cells, tc)
+		}
+		rows = append(rows, cells)
+	}
+	return r.c.formatTable(rows)
+}
+
+// pipeTable renders a GFM pipe table. Cells must already be escaped and on
+// a single line. A nil header renders as an empty header row, since GFM
+// tables require one.
+func pipeTable(header []string, rows [][]string) string {
+	width := len(header)
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	writeRow := func(b *strings.Builder, cells []string) {
+		b.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	var b strings.Builder
+	writeRow(&b, header)
+	b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows {
+		writeRow(&b, row)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (r *adfRenderer) inline(nodes []adfNode) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n.Type {
+		case "text":
+			b.WriteString(r.applyMarks(n.Text, n.Marks))
+		case "hardBreak":
+			b.WriteString("  \n")
+		case "mention":
+			b.WriteString(r.mention(n))
+		case "status":
+			colour := adfString(n.Attrs, "color")
+			if colour == "neutral" {
+				colour = "grey"
+			}
+			b.WriteString(r.renderMacro(r.newMacro("status", map[string]string{"title": adfString(n.Attrs, "text"), "colour": colour}, true)))
+		case "emoji":
+			if text := adfString(n.Attrs, "text"); text != "" {
+				b.WriteString(text)
+			} else {
+				b.WriteString(adfString(n.Attrs, "shortName"))
+			}
+		case "date":
+			if ms, err := strconv.ParseInt(adfString(n.Attrs, "timestamp"), 10, 64); err == nil {
+				b.WriteString(time.UnixMilli(ms).UTC().Format("2006-01-02"))
+			}
+		case "inlineCard":
+			href := adfString(n.Attrs, "url")
+			b.WriteString(r.link(href, href))
+		case "inlineExtension":
+			b.WriteString(r.renderMacro(r.extensionMacro(n, true)))
+		case "placeholder":
+			// Template placeholders have no Markdown form.
+		default:
+			b.WriteString(r.inline(n.Content))
+		}
+	}
+	return b.String()
+}
+
+func (r *adfRenderer) applyMarks(text string, marks []adfMark) string {
+	for _, m := range marks {
+		if m.Type == "code" {
+			fence := "`"
+			for strings.Contains(text, fence) {
+				fence += "`"
+			}
+			text = fence + text + fence
+			return r.wrapLink(text, marks)
+		}
+	}
+
+	text = markdownEscaper.Replace(text)
+	for _, m := range marks {
+		switch m.Type {
+		case "strong":
+			text = "**" + text + "**"
+		case "em":
+			text = "*" + text + "*"
+		case "strike":
+			text = "~~" + text + "~~"
+		case "subsup":
+			if adfString(m.Attrs, "type") == "sub" {
+				text = "<sub>" + text + "</sub>"
+			} else {
+				text = "<sup>" + text + "</sup>"
+			}
+		}
+	}
+	return r.wrapLink(text, marks)
+}
+
+func (r *adfRenderer) wrapLink(text string, marks []adfMark) string {
+	for _, m := range marks {
+		if m.Type == "link" {
+			return r.link(text, adfString(m.Attrs, "href"))
+		}
+	}
+	return text
+}
+
+// link renders a link. With wikilinks, links to Confluence pages become
+// [[Title|text]], taking the title from the page URL.
+func (r *adfRenderer) link(text, href string) string {
+	if r.c.flavor.WikiLinks {
+		if title, anchor, ok := pageURLTitle(href); ok {
+			target := FileName(title)
+			if anchor != "" {
+				target += "#" + anchor
+			}
+			if text == "" || text == href || text == target {
+				return "[[" + target + "]]"
+			}
+			return "[[" + target + "|" + text + "]]"
+		}
+	}
+	return "[" + text + "](" + href + ")"
+}
+
+// pageURLTitle returns the page title and anchor from a Confluence page
+// URL such as /wiki/spaces/ENG/pages/123/Runbook+Guide#Rollback. ok is
+// false for other URLs and page URLs without a title.
+func pageURLTitle(href string) (title, anchor string, ok bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", "", false
+	}
+	_, rest, found := strings.Cut(u.Path, "/spaces/")
+	if !found {
+		return "", "", false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) < 4 || parts[1] != "pages" || parts[3] == "" {
+		return "", "", false
+	}
+	title, err = url.QueryUnescape(parts[3])
+	if err != nil {
+		return "", "", false
+	}
+	return title, u.Fragment, true
+}
+
+// listItem prefixes the first line of content with marker and indents the
+// remaining lines to line up under it.
+func listItem(marker, content string) string {
+	lines := strings.Split(content, "\n")
+	pad := strings.Repeat(" ", len(marker))
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = pad + lines[i]
+		}
+	}
+	return marker + strings.Join(lines, "\n")
+}
+
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func quote(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func adfString(attrs map[string]interface{}, key string) string {
+	switch v := attrs[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func adfInt(attrs map[string]interface{}, key string, fallback int) int {
+	if v, ok := attrs[key].(float64); ok {
+		return int(v)
+	}
+	return fallback
+}
@@ -0,0 +1,244 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+func TestADFToMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "heading and marks",
+			input: `{"type":"doc","content":[{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Setup"}]},{"type":"paragraph","content":[{"type":"text","text":"bold","marks":[{"type":"strong"}]},{"type":"text","text":" and "},{"type":"text","text":"go build","marks":[{"type":"code"}]},{"type":"text","text":" see "},{"type":"text","text":"docs","marks":[{"type":"link","attrs":{"href":"https://example.com"}}]}]}]}`,
+			want:  "## Setup\n\n**bold** and `go build` see [docs](https://example.com)",
+		},
+		{
+			name:  "nested lists",
+			input: `{"type":"doc","content":[{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]},{"type":"orderedList","attrs":{"order":3},"content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"three"}]}]}]}]}]}]}`,
+			want:  "- one\n  3. three",
+		},
+		{
+			name:  "task list",
+			input: `{"type":"doc","content":[{"type":"taskList","content":[{"type":"taskItem","attrs":{"state":"DONE"},"content":[{"type":"text","text":"shipped"}]},{"type":"taskItem","attrs":{"state":"TODO"},"content":[{"type":"text","text":"announce"}]}]}]}`,
+			want:  "- [x] shipped\n- [ ] announce",
+		},
+		{
+			name:  "panel becomes alert",
+			input: `{"type":"doc","content":[{"type":"panel","attrs":{"panelType":"warning"},"content":[{"type":"paragraph","content":[{"type":"text","text":"Careful"}]}]}]}`,
+			want:  "> [!WARNING]\n> Careful",
+		},
+		{
+			name:  "mention and status",
+			input: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Owner: "},{"type":"mention","attrs":{"id":"abc","text":"@Jane Doe"}},{"type":"text","text":" "},{"type":"status","attrs":{"text":"in progress","color":"blue"}}]}]}`,
+			want:  "Owner: @Jane Doe [IN PROGRESS]",
+		},
+		{
+			name:  "expand",
+			input: `{"type":"doc","content":[{"type":"expand","attrs":{"title":"Details"},"content":[{"type":"paragraph","content":[{"type":"text","text":"Hidden"}]}]}]}`,
+			want:  "<details>\n<summary>Details</summary>\n\nHidden\n\n</details>",
+		},
+		{
+			name:  "expand title with markup",
+			input: `{"type":"doc","content":[{"type":"expand","attrs":{"title":"a <b> c"},"content":[{"type":"paragraph","content":[{"type":"text","text":"Hidden"}]}]}]}`,
+			want:  "<details>\n<summary>a &lt;b&gt; c</summary>\n\nHidden\n\n</details>",
+		},
+		{
+			name:  "code block",
+			input: `{"type":"doc","content":[{"type":"codeBlock","attrs":{"language":"go"},"content":[{"type":"text","text":"fmt.Println(1)"}]}]}`,
+			want:  "```go\nfmt.Println(1)\n```",
+		},
+		{
+			name:  "media",
+			input: `{"type":"doc","content":[{"type":"mediaSingle","content":[{"type":"media","attrs":{"type":"external","url":"https://example.com/a.png","alt":"diagram"}}]}]}`,
+			want:  "![diagram](https://example.com/a.png)",
+		},
+		{
+			name:  "table with header row",
+			input: `{"type":"doc","content":[{"type":"table","content":[{"type":"tableRow","content":[{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"Name"}]}]},{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"Value"}]}]}]},{"type":"tableRow","content":[{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"text","text":"a|b"}]}]},{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"text","text":"1"}]},{"type":"paragraph","content":[{"type":"text","text":"2"}]}]}]}]}]}`,
+			want:  "| Name | Value |\n| --- | --- |\n| a\\|b | 1<br>2 |",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ADFToMarkdown(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ADFToMarkdown() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFToMarkdown_Flavors(t *testing.T) {
+	input := `{"type":"doc","content":[` +
+		`{"type":"panel","attrs":{"panelType":"warning"},"content":[{"type":"paragraph","content":[{"type":"text","text":"Careful"}]}]},` +
+		`{"type":"paragraph","content":[{"type":"text","text":"See "},{"type":"text","text":"the runbook","marks":[{"type":"link","attrs":{"href":"https://example.atlassian.net/wiki/spaces/ENG/pages/123/Runbook+Guide#Rollback"}}]},{"type":"text","text":"."}]}` +
+		`]}`
+
+	tests := []struct {
+		flavor string
+		want   string
+	}{
+		{"gfm", "> [!WARNING]\n> Careful\n\nSee [the runbook](https://example.atlassian.net/wiki/spaces/ENG/pages/123/Runbook+Guide#Rollback)."},
+		{"obsidian", "> [!warning]\n> Careful\n\nSee [[Runbook Guide#Rollback|the runbook]]."},
+		{"mkdocs", "!!! danger\n\n    Careful\n\nSee [the runbook](https://example.atlassian.net/wiki/spaces/ENG/pages/123/Runbook+Guide#Rollback)."},
+		{"docusaurus", ":::danger\n\nCareful\n\n:::\n\nSee [the runbook](https://example.atlassian.net/wiki/spaces/ENG/pages/123/Runbook+Guide#Rollback)."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flavor, func(t *testing.T) {
+			got, err := NewConverter(WithFlavor(Flavors[tt.flavor])).adfToMarkdown(input, renderContext{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFToMarkdown_Attachments(t *testing.T) {
+	input := `{"type":"doc","content":[{"type":"mediaSingle","content":[{"type":"media","attrs":{"type":"file","id":"6e2b","collection":"contentId-1","__fileName":"flow chart.png","alt":"Flow"}}]},` +
+		`{"type":"mediaSingle","content":[{"type":"media","attrs":{"type":"file","id":"7f3c","alt":"logo.png"}}]},` +
+		`{"type":"mediaSingle","content":[{"type":"media","attrs":{"type":"file","id":"8a4d"}}]}]}`
+	page := &confluence.Page{ID: "1"}
+	ctx := renderContext{page: page, from: page}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"file names", nil, "![Flow](<flow chart.png>)\n\n![logo.png](logo.png)\n\n<!-- attachment 8a4d has no file name -->"},
+		{"resolver", []Option{WithAttachments(fakeAttachments{})}, "![Flow](<attachments/1/flow chart.png>)\n\n![logo.png](attachments/1/logo.png)\n\n<!-- attachment 8a4d has no file name -->"},
+		{"obsidian", []Option{WithFlavor(Flavors["obsidian"])}, "![[flow chart.png]]\n\n![[logo.png]]\n\n<!-- attachment 8a4d has no file name -->"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewConverter(tt.opts...).adfToMarkdown(input, ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFToMarkdown_Macros(t *testing.T) {
+	users := fakeUsers{
+		"5b10ac": {AccountID: "5b10ac", DisplayName: "Jane Doe", ProfileURL: "https://example.atlassian.net/wiki/people/5b10ac"},
+	}
+	toc := `{"type":"doc","content":[` +
+		`{"type":"extension","attrs":{"extensionType":"com.atlassian.confluence.macro.core","extensionKey":"toc","parameters":{"macroParams":{"maxLevel":{"value":"1"}}}}},` +
+		`{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Setup"}]},` +
+		`{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Install"}]}]}`
+	unknown := `{"type":"doc","content":[` +
+		`{"type":"extension","attrs":{"extensionKey":"roadmap","parameters":{"macroParams":{"source":{"value":"q3"}}}}},` +
+		`{"type":"bodiedExtension","attrs":{"extensionKey":"details"},"content":[{"type":"paragraph","content":[{"type":"text","text":"Body"}]}]}]}`
+	inline := `{"type":"doc","content":[{"type":"paragraph","content":[` +
+		`{"type":"mention","attrs":{"id":"5b10ac"}},{"type":"text","text":" "},` +
+		`{"type":"status","attrs":{"text":"in progress","color":"blue"}}]}]}`
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		input string
+		want  string
+	}{
+		{"toc", nil, toc, "- [Setup](#setup)\n\n# Setup\n\n## Install"},
+		{"unknown kept", nil, unknown, "Body"},
+		{"unknown dropped", []Option{WithUnknownMacroPolicy(UnknownMacroDrop)}, unknown, ""},
+		{"unknown as comments", []Option{WithUnknownMacroPolicy(UnknownMacroComment)}, unknown, "<!-- confluence macro: roadmap source=\"q3\" -->\n\n<!-- confluence macro: details -->"},
+		{"mention and status defaults", nil, inline, "@5b10ac [IN PROGRESS]"},
+		{"mention and status resolved", []Option{WithUserResolver(users, true), WithStatusEmoji(true)}, inline, "[@Jane Doe](https://example.atlassian.net/wiki/people/5b10ac) 🔵 [IN PROGRESS]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewConverter(tt.opts...).adfToMarkdown(tt.input, renderContext{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFToMarkdown_ListIndent(t *testing.T) {
+	input := `{"type":"doc","content":[{"type":"bulletList","content":[{"type":"listItem","content":[` +
+		`{"type":"paragraph","content":[{"type":"text","text":"one"}]},` +
+		`{"type":"orderedList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}]}` +
+		`]}]}]}`
+
+	tests := []struct {
+		flavor string
+		want   string
+	}{
+		{"gfm", "- one\n  1. two"},
+		{"mkdocs", "-   one\n    1.  two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flavor, func(t *testing.T) {
+			got, err := NewConverter(WithFlavor(Flavors[tt.flavor])).adfToMarkdown(input, renderContext{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFToMarkdown_BlockTableCells(t *testing.T) {
+	input := `{"type":"doc","content":[{"type":"table","content":[` +
+		`{"type":"tableRow","content":[{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"Step"}]}]}]},` +
+		`{"type":"tableRow","content":[{"type":"tableCell","content":[{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]}]},{"type":"codeBlock","content":[{"type":"text","text":"make"}]}]}]}` +
+		`]}]}`
+
+	tests := []struct {
+		style TableStyle
+		want  string
+	}{
+		{TableHTML, "<table>\n  <tr>\n    <th>\n\nStep\n\n</th>\n  </tr>\n  <tr>\n    <td>\n\n- one\n\n```\nmake\n```\n\n</td>\n  </tr>\n</table>"},
+		{TableGrid, "+-------+\n| Step  |\n+=======+\n| - one |\n|       |\n| ```   |\n| make  |\n| ```   |\n+-------+"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			got, err := NewConverter(WithTableStyle(tt.style)).adfToMarkdown(input, renderContext{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFToMarkdown_Invalid(t *testing.T) {
+	for _, input := range []string{`not json`, `{"type":"paragraph"}`} {
+		if _, err := ADFToMarkdown(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		} else if !strings.Contains(err.Error(), "ADF") {
+			t.Errorf("expected ADF parse error, got: %v", err)
+		}
+	}
+}
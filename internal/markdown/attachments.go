@@ -0,0 +1,112 @@
+package markdown
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// AttachmentResolver makes a page's attachments available next to the
+// converted Markdown, e.g. by downloading them, and returns the link to
+// use for each one. HasAttachment reports whether a page has an
+// attachment, for choosing between a diagram's exports.
+type AttachmentResolver interface {
+	Attachment(page *confluence.Page, filename string) (string, error)
+	HasAttachment(page *confluence.Page, filename string) bool
+}
+
+// WithAttachments resolves attached images and diagram exports through r.
+// Without it, attachments are referenced by their file name.
+func WithAttachments(r AttachmentResolver) Option {
+	return func(c *Converter) {
+		c.attachments = r
+	}
+}
+
+// attachmentLink returns the link for one of the current page's
+// attachments, falling back to its file name.
+func (c *Converter) attachmentLink(ctx renderContext, filename string) string {
+	if c.attachments == nil || ctx.page == nil {
+		return filename
+	}
+	link, err := c.attachments.Attachment(ctx.page, filename)
+	if err != nil {
+		return filename
+	}
+	return link
+}
+
+// attachmentEmbed returns Markdown showing one of the current page's
+// attachments as an image: an Obsidian embed with wikilinks, a Markdown
+// image otherwise.
+func (c *Converter) attachmentEmbed(ctx renderContext, filename, alt string) string {
+	link := c.attachmentLink(ctx, filename)
+	if c.flavor.WikiLinks {
+		return "![[" + wikiAttachmentName(link) + "]]"
+	}
+	return "![" + markdownEscaper.Replace(alt) + "](" + markdownLinkDestination(link) + ")"
+}
+
+// wikiAttachmentName returns the file name Obsidian finds an attachment
+// link by. Obsidian resolves embeds by name anywhere in the vault.
+func wikiAttachmentName(link string) string {
+	if unescaped, err := url.PathUnescape(link); err == nil {
+		link = unescaped
+	}
+	return path.Base(link)
+}
+
+// resolveAttachments points images of the current page's attachments at
+// the links returned by the attachment resolver. With wikilinks, images
+// and links to attachments become Obsidian embeds and links instead.
+func (c *Converter) resolveAttachments(n *storage.Node, ph *placeholders, ctx renderContext) {
+	if c.attachments == nil && !c.flavor.WikiLinks {
+		return
+	}
+	for _, image := range n.FindAll("ac:image") {
+		a := image.Child("ri:attachment")
+		// Attachments of other pages are left as file names
+		if a == nil || a.Child("ri:page") != nil {
+			continue
+		}
+		link := c.attachmentLink(ctx, a.Attr("ri:filename"))
+		if c.flavor.WikiLinks {
+			embed := "![[" + wikiAttachmentName(link)
+			if width := image.Attr("ac:width"); width != "" {
+				embed += "|" + width
+			}
+			image.ReplaceWith(storage.NewText(ph.add(embed+"]]", true)))
+			continue
+		}
+		a.ReplaceWith(storage.NewElement("ri:url", storage.Attr{Name: "ri:value", Value: link}))
+	}
+
+	if !c.flavor.WikiLinks {
+		return
+	}
+	for _, link := range n.FindAll("ac:link") {
+		a := link.Child("ri:attachment")
+		if a == nil || a.Child("ri:page") != nil {
+			continue
+		}
+		name := wikiAttachmentName(c.attachmentLink(ctx, a.Attr("ri:filename")))
+		text := strings.TrimSpace(link.Text())
+		wikilink := "[[" + name + "]]"
+		if text != "" && text != name && text != a.Attr("ri:filename") {
+			wikilink = "[[" + name + "|" + text + "]]"
+		}
+		link.ReplaceWith(storage.NewText(ph.add(wikilink, true)))
+	}
+}
+
+// markdownLinkDestination wraps a link destination in angle brackets when
+// it contains characters that would end it early.
+func markdownLinkDestination(link string) string {
+	if strings.ContainsAny(link, " ()") {
+		return "<" + link + ">"
+	}
+	return link
+}
@@ -0,0 +1,212 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// builtinMacros returns the handlers every Converter starts with.
+func builtinMacros() map[string]MacroHandler {
+	return map[string]MacroHandler{
+		"code":     MacroHandlerFunc(renderCodeMacro),
+		"noformat": MacroHandlerFunc(renderCodeMacro),
+		"info":     MacroHandlerFunc(renderPanelMacro),
+		"note":     MacroHandlerFunc(renderPanelMacro),
+		"tip":      MacroHandlerFunc(renderPanelMacro),
+		"warning":  MacroHandlerFunc(renderPanelMacro),
+		"panel":    MacroHandlerFunc(renderPanelMacro),
+		"expand":   MacroHandlerFunc(renderExpandMacro),
+		"status":   MacroHandlerFunc(renderStatusMacro),
+		"anchor":   MacroHandlerFunc(renderAnchorMacro),
+		"toc":      MacroHandlerFunc(renderTOCMacro),
+
+		"include":         MacroHandlerFunc(renderIncludeMacro),
+		"excerpt-include": MacroHandlerFunc(renderExcerptIncludeMacro),
+		"excerpt":         MacroHandlerFunc(renderExcerptMacro),
+		"jira":            MacroHandlerFunc(renderJiraMacro),
+		"section":         MacroHandlerFunc(renderSectionMacro),
+		"column":          MacroHandlerFunc(renderColumnMacro),
+		"mermaid":         MacroHandlerFunc(renderDiagramSourceMacro),
+		"plantuml":        MacroHandlerFunc(renderDiagramSourceMacro),
+		"drawio":          MacroHandlerFunc(renderDiagramImageMacro),
+		"gliffy":          MacroHandlerFunc(renderDiagramImageMacro),
+
+		"mathinline":           MacroHandlerFunc(renderMathMacro),
+		"mathblock":            MacroHandlerFunc(renderMathMacro),
+		"mathjax-inline-macro": MacroHandlerFunc(renderMathMacro),
+		"mathjax-block-macro":  MacroHandlerFunc(renderMathMacro),
+		"latex-inline":         MacroHandlerFunc(renderMathMacro),
+		"latex":                MacroHandlerFunc(renderMathMacro),
+		"latex-formatting":     MacroHandlerFunc(renderMathMacro),
+	}
+}
+
+// statusEmoji maps status lozenge colours to a matching emoji.
+var statusEmoji = map[string]string{
+	"green":  "🟢",
+	"yellow": "🟡",
+	"red":    "🔴",
+	"blue":   "🔵",
+	"grey":   "⚪",
+	"purple": "🟣",
+}
+
+// renderCodeMacro renders code and noformat macros as fenced code blocks.
+func renderCodeMacro(m *Macro) (string, error) {
+	return m.converter.fencedCode(m.Parameters["language"], m.PlainTextBody()), nil
+}
+
+// fencedCode renders code as a fenced code block in the flavor's fence
+// style, lengthening the fence if the code itself contains one.
+func (c *Converter) fencedCode(lang, code string) string {
+	code = strings.TrimSuffix(code, "\n")
+	fence := c.flavor.Fence
+	for strings.Contains(code, fence) {
+		fence += fence[:1]
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+// renderPanelMacro renders info, note, tip and warning macros as
+// admonitions in the flavor's syntax, and generic panels as plain
+// blockquotes.
+func renderPanelMacro(m *Macro) (string, error) {
+	body, err := m.Body()
+	if err != nil {
+		return "", err
+	}
+
+	return m.converter.admonition(panelAlerts[m.Name], m.Parameters["title"], body), nil
+}
+
+// renderExpandMacro renders an expand macro as a collapsible
+// <details> block.
+func renderExpandMacro(m *Macro) (string, error) {
+	body, err := m.Body()
+	if err != nil {
+		return "", err
+	}
+
+	title := m.Parameters["title"]
+	if title == "" {
+		title = "Click here to expand..."
+	}
+	return "<details>\n<summary>" + html.EscapeString(title) + "</summary>\n\n" + body + "\n\n</details>", nil
+}
+
+// renderStatusMacro renders a status lozenge as bracketed upper-case
+// text, optionally prefixed with an emoji for its colour.
+func renderStatusMacro(m *Macro) (string, error) {
+	status := "[" + strings.ToUpper(m.Parameters["title"]) + "]"
+	if m.converter.statusEmoji {
+		colour := strings.ToLower(m.Parameters["colour"])
+		if colour == "" {
+			colour = "grey"
+		}
+		if emoji, ok := statusEmoji[colour]; ok {
+			status = emoji + " " + status
+		}
+	}
+	return status, nil
+}
+
+// renderAnchorMacro renders an anchor as an empty HTML anchor, so links to
+// "#name" keep working.
+func renderAnchorMacro(m *Macro) (string, error) {
+	name := m.Parameters[""]
+	if name == "" {
+		return "", nil
+	}
+	return `<a id="` + html.EscapeString(name) + `"></a>`, nil
+}
+
+// renderTOCMacro renders a table of contents from the page's headings,
+// linking to GitHub-style heading anchors.
+func renderTOCMacro(m *Macro) (string, error) {
+	minLevel := macroIntParameter(m, "minLevel", 1)
+	maxLevel := macroIntParameter(m, "maxLevel", 6)
+
+	var headings []heading
+	for _, h := range m.ctx.headings {
+		if h.level >= minLevel && h.level <= maxLevel {
+			headings = append(headings, h)
+		}
+	}
+	if len(headings) == 0 {
+		return "", nil
+	}
+
+	top := headings[0].level
+	for _, h := range headings {
+		top = min(top, h.level)
+	}
+
+	f := m.converter.flavor
+	bullet := f.listMarker(f.BulletMarker + " ")
+	slugs := newSlugger()
+	var lines []string
+	for _, h := range headings {
+		indent := strings.Repeat(" ", len(bullet)*(h.level-top))
+		lines = append(lines, fmt.Sprintf("%s%s[%s](#%s)", indent, bullet, h.text, slugs.slug(h.text)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func macroIntParameter(m *Macro, name string, fallback int) int {
+	if v, err := strconv.Atoi(m.Parameters[name]); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// heading is a heading in a storage document.
+type heading struct {
+	level int
+	text  string
+}
+
+// collectHeadings lists the non-empty headings in doc, in document order.
+// It runs before macros are rendered, since rendering moves macro bodies
+// out of the document.
+func collectHeadings(doc *storage.Node) []heading {
+	var headings []heading
+	doc.Walk(func(n *storage.Node) bool {
+		if n.Type != storage.ElementNode || len(n.Name) != 2 || n.Name[0] != 'h' || n.Name[1] < '1' || n.Name[1] > '6' {
+			return true
+		}
+		if text := strings.Join(strings.Fields(n.Text()), " "); text != "" {
+			headings = append(headings, heading{level: int(n.Name[1] - '0'), text: text})
+		}
+		return false
+	})
+	return headings
+}
+
+var slugStrip = regexp.MustCompile(`[^\p{L}\p{N}\s_-]`)
+
+// slugger generates GitHub-style heading anchors, numbering repeats the
+// same way GitHub does ("setup", "setup-1", ...).
+type slugger struct {
+	seen map[string]int
+}
+
+func newSlugger() *slugger {
+	return &slugger{seen: map[string]int{}}
+}
+
+func (s *slugger) slug(text string) string {
+	slug := slugStrip.ReplaceAllString(strings.ToLower(strings.TrimSpace(text)), "")
+	slug = strings.ReplaceAll(slug, " ", "-")
+
+	n := s.seen[slug]
+	s.seen[slug] = n + 1
+	if n > 0 {
+		return fmt.Sprintf("%s-%d", slug, n)
+	}
+	return slug
+}
@@ -0,0 +1,178 @@
+package markdown
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of a Markdown document small enough for a size budget.
+type Chunk struct {
+	// Breadcrumb is the heading path of the section the chunk starts in.
+	Breadcrumb []string
+	Text       string
+}
+
+// SizeFunc measures text against a chunk budget.
+type SizeFunc func(string) int
+
+// CountChars measures text in characters.
+func CountChars(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// EstimateTokens approximates the number of LLM tokens in text at four
+// characters per token, which is close enough for English prose and
+// Markdown without needing a model-specific tokenizer.
+func EstimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// SplitChunks splits md into chunks of at most limit, as measured by size.
+// Chunks follow the heading structure: a section below level 1 is kept
+// with its subsections when they fit together, and a chunk never spans
+// into a section outside the one it starts in, so its breadcrumb covers
+// all of its text. Level-1 sections, such as the page title, keep only
+// their own text, since they usually span the whole page. Sections that
+// don't fit on their own are split between paragraphs, then between lines.
+// Fenced code blocks are only split when a block alone exceeds the limit.
+func SplitChunks(md string, limit int, size SizeFunc) []Chunk {
+	lines := strings.Split(md, "\n")
+	sections := Sections(md)
+
+	type segment struct {
+		start, end int
+		section    *Section
+	}
+	var segments []segment
+	if len(sections) == 0 || sections[0].Start > 0 {
+		end := len(lines)
+		if len(sections) > 0 {
+			end = sections[0].Start
+		}
+		segments = append(segments, segment{start: 0, end: end})
+	}
+	for i := range sections {
+		end := len(lines)
+		if i+1 < len(sections) {
+			end = sections[i+1].Start
+		}
+		segments = append(segments, segment{start: sections[i].Start, end: end, section: &sections[i]})
+	}
+
+	var chunks []Chunk
+	var current *Chunk
+	currentEnd := 0 // end line of the section the current chunk starts in
+	for _, seg := range segments {
+		text := strings.TrimSpace(strings.Join(lines[seg.start:seg.end], "\n"))
+		if text == "" {
+			continue
+		}
+		pieces := []string{text}
+		if size(text) > limit {
+			pieces = splitBlocks(text, limit, size)
+		}
+		for _, piece := range pieces {
+			if current != nil && seg.start < currentEnd && size(current.Text+"\n\n"+piece) <= limit {
+				current.Text += "\n\n" + piece
+				continue
+			}
+			chunks = append(chunks, Chunk{Text: piece})
+			current = &chunks[len(chunks)-1]
+			currentEnd = seg.end
+			if seg.section != nil {
+				current.Breadcrumb = seg.section.Path
+				if seg.section.Level > 1 {
+					currentEnd = seg.section.End
+				}
+			}
+		}
+	}
+	return chunks
+}
+
+// splitBlocks packs the paragraphs of text into pieces of at most limit.
+func splitBlocks(text string, limit int, size SizeFunc) []string {
+	var pieces []string
+	current := ""
+	add := func(block string) {
+		if current != "" && size(current+"\n\n"+block) <= limit {
+			current += "\n\n" + block
+			return
+		}
+		if current != "" {
+			pieces = append(pieces, current)
+		}
+		current = block
+	}
+
+	for _, block := range paragraphs(text) {
+		if size(block) <= limit {
+			add(block)
+			continue
+		}
+		for _, line := range splitLines(block, limit, size) {
+			add(line)
+		}
+	}
+	if current != "" {
+		pieces = append(pieces, current)
+	}
+	return pieces
+}
+
+// paragraphs splits text on blank lines outside fenced code blocks.
+func paragraphs(text string) []string {
+	var blocks []string
+	var current []string
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+		} else if m := fenceOpening.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+		} else if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
+
+// splitLines packs the lines of block into pieces of at most limit, cutting
+// lines that are too long on their own.
+func splitLines(block string, limit int, size SizeFunc) []string {
+	var pieces []string
+	current := ""
+	for _, line := range strings.Split(block, "\n") {
+		if current != "" && size(current+"\n"+line) <= limit {
+			current += "\n" + line
+			continue
+		}
+		if current != "" {
+			pieces = append(pieces, current)
+		}
+		current = line
+		for size(current) > limit {
+			runes := []rune(current)
+			// The longest prefix that fits, keeping at least one character.
+			n := sort.Search(len(runes), func(i int) bool { return size(string(runes[:i+1])) > limit })
+			n = max(n, 1)
+			pieces = append(pieces, string(runes[:n]))
+			current = string(runes[n:])
+		}
+	}
+	if current != "" {
+		pieces = append(pieces, current)
+	}
+	return pieces
+}
@@ -0,0 +1,124 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitChunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  []Chunk
+	}{
+		{
+			name:  "whole document fits",
+			input: "# Runbook\n\nIntro\n\n## Setup\n\nSteps\n",
+			limit: 100,
+			want: []Chunk{
+				{Breadcrumb: []string{"Runbook"}, Text: "# Runbook\n\nIntro"},
+				{Breadcrumb: []string{"Runbook", "Setup"}, Text: "## Setup\n\nSteps"},
+			},
+		},
+		{
+			name:  "sections split at headings",
+			input: "# Runbook\n\nIntro\n\n## Setup\n\nInstall the tools.\n\n### Install\n\nRun it.\n\n## Deploy\n\nShip it.\n",
+			limit: 40,
+			want: []Chunk{
+				{Breadcrumb: []string{"Runbook"}, Text: "# Runbook\n\nIntro"},
+				{Breadcrumb: []string{"Runbook", "Setup"}, Text: "## Setup\n\nInstall the tools."},
+				{Breadcrumb: []string{"Runbook", "Setup", "Install"}, Text: "### Install\n\nRun it."},
+				{Breadcrumb: []string{"Runbook", "Deploy"}, Text: "## Deploy\n\nShip it."},
+			},
+		},
+		{
+			name:  "subsections kept with their section",
+			input: "# Runbook\n\n## Setup\n\n### Install\n\nRun it.\n\n## Deploy\n\nShip it.\n",
+			limit: 100,
+			want: []Chunk{
+				{Breadcrumb: []string{"Runbook"}, Text: "# Runbook"},
+				{Breadcrumb: []string{"Runbook", "Setup"}, Text: "## Setup\n\n### Install\n\nRun it."},
+				{Breadcrumb: []string{"Runbook", "Deploy"}, Text: "## Deploy\n\nShip it."},
+			},
+		},
+		{
+			name:  "chunk does not leave its section",
+			input: "## Setup\n\n### Install\n\nRun it.\n\n## Deploy\n\nShip it.\n",
+			limit: 40,
+			want: []Chunk{
+				{Breadcrumb: []string{"Setup"}, Text: "## Setup\n\n### Install\n\nRun it."},
+				{Breadcrumb: []string{"Deploy"}, Text: "## Deploy\n\nShip it."},
+			},
+		},
+		{
+			name:  "long section split between paragraphs",
+			input: "## Notes\n\nFirst paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n",
+			limit: 40,
+			want: []Chunk{
+				{Breadcrumb: []string{"Notes"}, Text: "## Notes\n\nFirst paragraph."},
+				{Breadcrumb: []string{"Notes"}, Text: "Second paragraph.\n\nThird paragraph."},
+			},
+		},
+		{
+			name:  "code block kept whole",
+			input: "## Code\n\n```\na\n\nb\n```\n",
+			limit: 20,
+			want: []Chunk{
+				{Breadcrumb: []string{"Code"}, Text: "## Code"},
+				{Breadcrumb: []string{"Code"}, Text: "```\na\n\nb\n```"},
+			},
+		},
+		{
+			name:  "long line cut",
+			input: strings.Repeat("x", 25),
+			limit: 10,
+			want: []Chunk{
+				{Text: strings.Repeat("x", 10)},
+				{Text: strings.Repeat("x", 10)},
+				{Text: strings.Repeat("x", 5)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitChunks(tt.input, tt.limit, CountChars)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected:\n%#v\ngot:\n%#v", tt.want, got)
+			}
+			for _, c := range got {
+				if CountChars(c.Text) > tt.limit {
+					t.Errorf("chunk over limit: %q", c.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitChunks_Page(t *testing.T) {
+	page := storagePage("1", "OPS", "Runbook", `<p>How we ship.</p><h2>Setup</h2><p>Install the tools.</p><h2>Rollback</h2><p>Redeploy the previous tag.</p>`)
+	md, err := NewConverter().PageToMarkdown(page, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Chunk{
+		{Breadcrumb: []string{"Runbook"}, Text: "# Runbook\n\nHow we ship."},
+		{Breadcrumb: []string{"Runbook", "Setup"}, Text: "## Setup\n\nInstall the tools."},
+		{Breadcrumb: []string{"Runbook", "Rollback"}, Text: "## Rollback\n\nRedeploy the previous tag."},
+	}
+	if got := SplitChunks(md, 1000, CountChars); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", want, got)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("expected 2 tokens, got %d", got)
+	}
+	if got := EstimateTokens("abcdefghi"); got != 3 {
+		t.Errorf("expected 3 tokens, got %d", got)
+	}
+}
@@ -0,0 +1,126 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// CommentResolver fetches the inline comments on a page.
+// *confluence.Client implements it.
+type CommentResolver interface {
+	GetInlineComments(pageID string) ([]confluence.Comment, error)
+}
+
+// WithInlineCommentFootnotes fetches each page's inline comments through r
+// and attaches them as Markdown footnotes ([^1]) at the text they
+// highlight. Without it, comment markers are dropped and their text kept.
+func WithInlineCommentFootnotes(r CommentResolver) Option {
+	return func(c *Converter) {
+		c.comments = r
+	}
+}
+
+// footnotes tracks the inline comments of the page being converted and
+// the footnote numbers handed out for them.
+type footnotes struct {
+	comments map[string][]confluence.Comment // by marker ref
+	numbers  map[string]int
+	order    []string // marker refs in footnote order
+}
+
+func (c *Converter) loadFootnotes(page *confluence.Page) (*footnotes, error) {
+	comments, err := c.comments.GetInlineComments(page.ID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching inline comments: %w", err)
+	}
+	f := &footnotes{comments: map[string][]confluence.Comment{}, numbers: map[string]int{}}
+	for _, comment := range comments {
+		if comment.MarkerRef != "" {
+			f.comments[comment.MarkerRef] = append(f.comments[comment.MarkerRef], comment)
+		}
+	}
+	return f, nil
+}
+
+func (f *footnotes) token(n int) string {
+	return fmt.Sprintf("confluencemdnote%dend", n)
+}
+
+// mark replaces the inline comment markers in n's subtree with their text.
+// The last marker for each commented selection (a selection spanning
+// formatting is split into several markers) is followed by a placeholder
+// for its footnote reference.
+func (f *footnotes) mark(n *storage.Node) {
+	markers := n.FindAll("ac:inline-comment-marker")
+	last := map[string]*storage.Node{}
+	for _, m := range markers {
+		last[m.Attr("ac:ref")] = m
+	}
+
+	for _, m := range markers {
+		ref := m.Attr("ac:ref")
+		if last[ref] != m || len(f.comments[ref]) == 0 {
+			m.Unwrap()
+			continue
+		}
+		num, ok := f.numbers[ref]
+		if !ok {
+			f.order = append(f.order, ref)
+			num = len(f.order)
+			f.numbers[ref] = num
+		}
+		m.ReplaceWith(append(append([]*storage.Node(nil), m.Children...), storage.NewText(f.token(num)))...)
+	}
+}
+
+// renderFootnotes turns footnote placeholders in md into references and
+// appends the footnote definitions.
+func (c *Converter) renderFootnotes(md string, f *footnotes, ctx renderContext) (string, error) {
+	if len(f.order) == 0 {
+		return md, nil
+	}
+
+	var defs []string
+	for i, ref := range f.order {
+		num := i + 1
+		md = strings.ReplaceAll(md, f.token(num), fmt.Sprintf("[^%d]", num))
+
+		var parts []string
+		for _, comment := range f.comments[ref] {
+			doc, err := storage.Parse(comment.Body)
+			if err != nil {
+				return "", fmt.Errorf("parsing comment %s: %w", comment.ID, err)
+			}
+			body, err := c.nodesToMarkdown(doc.Children, renderContext{page: ctx.page, from: ctx.from})
+			if err != nil {
+				return "", err
+			}
+			if author := c.commentAuthor(comment.Author); author != "" {
+				body = "**" + author + ":** " + body
+			}
+			parts = append(parts, body)
+		}
+
+		def := fmt.Sprintf("[^%d]: ", num) + strings.Join(parts, "\n\n")
+		lines := strings.SplitN(def, "\n", 2)
+		if len(lines) == 2 {
+			def = lines[0] + "\n" + indent(lines[1], "    ")
+		}
+		defs = append(defs, def)
+	}
+	return strings.TrimRight(md, "\n") + "\n\n" + strings.Join(defs, "\n") + "\n", nil
+}
+
+// commentAuthor returns a comment author's display name, looking it up
+// when the API only returned an account ID.
+func (c *Converter) commentAuthor(u confluence.User) string {
+	if u.DisplayName == "" && u.AccountID != "" && c.users != nil {
+		if user, err := c.users.GetUser(confluence.UserRef{AccountID: u.AccountID}); err == nil {
+			return user.Name()
+		}
+	}
+	return u.Name()
+}
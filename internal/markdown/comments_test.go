@@ -0,0 +1,38 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+type fakeComments []confluence.Comment
+
+func (f fakeComments) GetInlineComments(pageID string) ([]confluence.Comment, error) {
+	return f, nil
+}
+
+func TestInlineCommentFootnotes(t *testing.T) {
+	comments := fakeComments{
+		{ID: "10", MarkerRef: "ref-a", Author: confluence.User{DisplayName: "Jane Doe"}, Body: `<p>Is this <strong>still</strong> true?</p>`},
+		{ID: "11", MarkerRef: "ref-b", Author: confluence.User{AccountID: "5b10ac"}, Body: `<p>Typo.</p><p>Also see below.</p>`},
+		{ID: "12", MarkerRef: "ref-gone", Body: `<p>Orphaned</p>`},
+	}
+	users := fakeUsers{"5b10ac": {AccountID: "5b10ac", DisplayName: "John Smith"}}
+
+	input := `<p>The <ac:inline-comment-marker ac:ref="ref-a">service <em>scales</em></ac:inline-comment-marker>` +
+		`<ac:inline-comment-marker ac:ref="ref-a"> automatically</ac:inline-comment-marker>.</p>` +
+		`<ac:structured-macro ac:name="note"><ac:rich-text-body><p>Check the <ac:inline-comment-marker ac:ref="ref-b">teh</ac:inline-comment-marker> docs.</p></ac:rich-text-body></ac:structured-macro>` +
+		`<p><ac:inline-comment-marker ac:ref="ref-unknown">Unmatched</ac:inline-comment-marker> text.</p>`
+
+	c := NewConverter(WithInlineCommentFootnotes(comments), WithUserResolver(users, false))
+	got := convertStorage(t, c, input)
+	want := "The service _scales_ automatically[^1].\n\n" +
+		"> [!IMPORTANT]\n> Check the teh[^2] docs.\n\n" +
+		"Unmatched text.\n\n" +
+		"[^1]: **Jane Doe:** Is this **still** true?\n" +
+		"[^2]: **John Smith:** Typo.\n\n    Also see below.\n"
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
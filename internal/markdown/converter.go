@@ -2,67 +2,106 @@ package markdown
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
 	md "github.com/JohannesKaufmann/html-to-markdown"
 )
 
-type Converter struct {
-	converter *md.Converter
-}
+// BodyFormat selects which page body representation is converted.
+type BodyFormat string
+
+const (
+	BodyFormatStorage BodyFormat = "storage"
+	BodyFormatView    BodyFormat = "view"
+	BodyFormatADF     BodyFormat = "adf"
+)
 
-func NewConverter() *Converter {
-	converter := md.NewConverter("", true, nil)
-	return &Converter{
-		converter: converter,
+// ParseBodyFormat validates a --body-format flag value.
+func ParseBodyFormat(s string) (BodyFormat, error) {
+	switch f := BodyFormat(s); f {
+	case BodyFormatStorage, BodyFormatView, BodyFormatADF:
+		return f, nil
 	}
+	return "", fmt.Errorf("unknown body format %q (expected storage, view or adf)", s)
 }
 
-// preprocessConfluenceTasks converts Confluence ac:task elements to HTML checkboxes
-// before the markdown converter processes them.
-func preprocessConfluenceTasks(html string) string {
-	// First, replace task-list wrappers with ul tags (before processing tasks)
-	result := regexp.MustCompile(`<ac:task-list[^>]*>`).ReplaceAllString(html, "<ul>")
-	result = strings.ReplaceAll(result, "</ac:task-list>", "</ul>")
-
-	// Pattern to match individual tasks
-	// <ac:task>...<ac:task-status>complete|incomplete</ac:task-status>...<ac:task-body>...content...</ac:task-body>...</ac:task>
-	taskPattern := regexp.MustCompile(`(?s)<ac:task>.*?<ac:task-status>(\w+)</ac:task-status>.*?<ac:task-body[^>]*>(.*?)</ac:task-body>.*?</ac:task>`)
-
-	result = taskPattern.ReplaceAllStringFunc(result, func(match string) string {
-		submatches := taskPattern.FindStringSubmatch(match)
-		if len(submatches) < 3 {
-			return match
-		}
+type Converter struct {
+	converter     *md.Converter
+	bodyFormat    BodyFormat
+	macros        map[string]MacroHandler
+	unknownMacros UnknownMacroPolicy
+	statusEmoji   bool
+	includes      *includeResolver
+	users         UserResolver
+	userLinks     bool
+	jiraURL       string
+	jira          JiraResolver
+	tableStyle    TableStyle
+	layoutRules   bool
+	attachments   AttachmentResolver
+	comments      CommentResolver
+	pageLinks     PageLinker
+	flavor        Flavor
+}
 
-		status := submatches[1]
-		body := submatches[2]
+// renderContext describes the page a storage document belongs to.
+type renderContext struct {
+	page *confluence.Page
+	// from is the page whose Markdown is being written, which differs from
+	// page inside included content.
+	from *confluence.Page
+	// includeChain lists the IDs of pages being included, outermost first,
+	// for cycle detection.
+	includeChain []string
+	// notes collects inline comment footnotes, when enabled.
+	notes *footnotes
+	// headings lists the headings of the document being written, for
+	// tables of contents anywhere in it, including macro bodies.
+	headings []heading
+}
 
-		// Clean up the body - remove span wrappers and inline comment markers
-		body = regexp.MustCompile(`<span[^>]*>`).ReplaceAllString(body, "")
-		body = strings.ReplaceAll(body, "</span>", "")
-		body = regexp.MustCompile(`<ac:inline-comment-marker[^>]*>`).ReplaceAllString(body, "")
-		body = strings.ReplaceAll(body, "</ac:inline-comment-marker>", "")
-		body = strings.TrimSpace(body)
+// Option configures a Converter.
+type Option func(*Converter)
 
-		checkbox := "[ ]"
-		if status == "complete" {
-			checkbox = "[x]"
-		}
+// WithBodyFormat selects the page body representation to convert.
+func WithBodyFormat(format BodyFormat) Option {
+	return func(c *Converter) {
+		c.bodyFormat = format
+	}
+}
 
-		return fmt.Sprintf("<li>%s %s</li>", checkbox, body)
-	})
+// WithStatusEmoji prefixes status lozenges with an emoji for their colour.
+func WithStatusEmoji(enabled bool) Option {
+	return func(c *Converter) {
+		c.statusEmoji = enabled
+	}
+}
 
-	return result
+func NewConverter(opts ...Option) *Converter {
+	c := &Converter{
+		bodyFormat:    BodyFormatStorage,
+		macros:        builtinMacros(),
+		unknownMacros: UnknownMacroKeep,
+		tableStyle:    TableHTML,
+		flavor:        Flavors["gfm"],
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.converter = md.NewConverter("", true, c.flavor.markdownOptions())
+	c.converter.Before(c.flavor.padListPrefixes)
+	return c
 }
 
 func (c *Converter) PageToMarkdown(page *confluence.Page, includeMetadata bool) (string, error) {
 	var output strings.Builder
 
 	// Title as H1
-	output.WriteString(fmt.Sprintf("# %s\n\n", page.Title))
+	if c.flavor.TitleHeading {
+		output.WriteString(c.flavor.heading(1, page.Title) + "\n\n")
+	}
 
 	// Optional metadata
 	if includeMetadata {
@@ -70,10 +109,10 @@ func (c *Converter) PageToMarkdown(page *confluence.Page, includeMetadata bool)
 		output.WriteString(fmt.Sprintf("**Page ID:** %s\n\n", page.ID))
 		output.WriteString(fmt.Sprintf("**Created:** %s by %s\n\n",
 			page.History.CreatedDate.Format("2006-01-02"),
-			page.History.CreatedBy.DisplayName))
+			page.History.CreatedBy.Name()))
 		output.WriteString(fmt.Sprintf("**Last Modified:** %s by %s (v%d)\n\n",
 			page.Version.When.Format("2006-01-02"),
-			page.Version.By.DisplayName,
+			page.Version.By.Name(),
 			page.Version.Number))
 		if page.Version.Message != "" {
 			output.WriteString(fmt.Sprintf("**Version Message:** %s\n\n", page.Version.Message))
@@ -81,16 +120,86 @@ func (c *Converter) PageToMarkdown(page *confluence.Page, includeMetadata bool)
 		output.WriteString("---\n\n")
 	}
 
-	// Convert HTML content to Markdown
-	htmlContent := page.Body.Storage.Value
-	if htmlContent == "" {
-		htmlContent = page.Body.View.Value
+	markdown, err := c.bodyToMarkdown(page)
+	if err != nil {
+		return "", err
+	}
+
+	output.WriteString(markdown)
+
+	if c.flavor.MDX {
+		return toMDX(output.String()), nil
 	}
+	return output.String(), nil
+}
 
-	// Preprocess Confluence-specific elements
-	htmlContent = preprocessConfluenceTasks(htmlContent)
+// bodyToMarkdown converts the page body selected by the converter's body
+// format, falling back between storage and view HTML when one is empty.
+func (c *Converter) bodyToMarkdown(page *confluence.Page) (string, error) {
+	if c.bodyFormat == BodyFormatADF {
+		if page.Body.AtlasDocFormat.Value == "" {
+			return "", fmt.Errorf("page has no atlas_doc_format body")
+		}
+		return c.adfToMarkdown(page.Body.AtlasDocFormat.Value, renderContext{page: page, from: page})
+	}
 
-	markdown, err := c.converter.ConvertString(htmlContent)
+	// View HTML is rendered by Confluence and needs no preprocessing
+	if page.Body.Storage.Value == "" || (c.bodyFormat == BodyFormatView && page.Body.View.Value != "") {
+		return c.htmlToMarkdown(page.Body.View.Value)
+	}
+
+	doc, err := storage.Parse(page.Body.Storage.Value)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := renderContext{page: page, from: page, headings: collectHeadings(doc)}
+	if c.comments == nil {
+		return c.storageToMarkdown(doc, ctx)
+	}
+	if ctx.notes, err = c.loadFootnotes(page); err != nil {
+		return "", err
+	}
+	markdown, err := c.storageToMarkdown(doc, ctx)
+	if err != nil {
+		return "", err
+	}
+	return c.renderFootnotes(markdown, ctx.notes, ctx)
+}
+
+// storageToMarkdown converts a parsed storage-format document. Macros are
+// rendered by their handlers, and the remaining Confluence elements are
+// rewritten to plain HTML before conversion.
+func (c *Converter) storageToMarkdown(doc *storage.Node, ctx renderContext) (string, error) {
+	if ctx.notes != nil {
+		ctx.notes.mark(doc)
+	}
+
+	ph := &placeholders{}
+	if err := c.renderMacros(doc, ph, ctx); err != nil {
+		return "", err
+	}
+	c.convertMentions(doc)
+	if c.flavor.WikiLinks {
+		c.convertWikiLinks(doc, ph)
+	}
+	c.resolveAttachments(doc, ph, ctx)
+	c.resolvePageLinks(doc, ctx)
+	transformStorage(doc)
+	c.flattenLayouts(doc, ph)
+	if err := c.renderTables(doc, ph); err != nil {
+		return "", err
+	}
+
+	markdown, err := c.htmlToMarkdown(doc.HTML())
+	if err != nil {
+		return "", err
+	}
+	return ph.expand(markdown), nil
+}
+
+func (c *Converter) htmlToMarkdown(html string) (string, error) {
+	markdown, err := c.converter.ConvertString(html)
 	if err != nil {
 		return "", fmt.Errorf("converting HTML to markdown: %w", err)
 	}
@@ -99,7 +208,5 @@ func (c *Converter) PageToMarkdown(page *confluence.Page, includeMetadata bool)
 	markdown = strings.ReplaceAll(markdown, `\[x\]`, `[x]`)
 	markdown = strings.ReplaceAll(markdown, `\[ \]`, `[ ]`)
 
-	output.WriteString(markdown)
-
-	return output.String(), nil
+	return markdown, nil
 }
@@ -3,8 +3,23 @@ package markdown
 import (
 	"strings"
 	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
 )
 
+// preprocessStorage parses input and rewrites its Confluence elements to
+// plain HTML.
+func preprocessStorage(t *testing.T, input string) string {
+	t.Helper()
+	doc, err := storage.Parse(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	transformStorage(doc)
+	return doc.HTML()
+}
+
 func TestPreprocessConfluenceTasks(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -70,11 +85,36 @@ func TestPreprocessConfluenceTasks(t *testing.T) {
 			wantChecked:   nil,
 			wantUnchecked: []string{"Swap", "main services view", "dashboard"},
 		},
+		{
+			name: "elements in unexpected order",
+			input: `<ac:task-list>
+<ac:task>
+<ac:task-body><span>Status after body</span></ac:task-body>
+<ac:task-status>complete</ac:task-status>
+<ac:task-uuid>abc</ac:task-uuid>
+<ac:task-id>1</ac:task-id>
+</ac:task>
+</ac:task-list>`,
+			wantChecked:   []string{"Status after body"},
+			wantUnchecked: nil,
+		},
+		{
+			name: "task containing a link",
+			input: `<ac:task-list>
+<ac:task>
+<ac:task-id>1</ac:task-id>
+<ac:task-status>incomplete</ac:task-status>
+<ac:task-body>Read <a href="https://example.com/doc">the doc</a> first</ac:task-body>
+</ac:task>
+</ac:task-list>`,
+			wantChecked:   nil,
+			wantUnchecked: []string{`<a href="https://example.com/doc">the doc</a> first`},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := preprocessConfluenceTasks(tt.input)
+			result := preprocessStorage(t, tt.input)
 
 			// Check that completed tasks have [x]
 			for _, want := range tt.wantChecked {
@@ -122,9 +162,86 @@ func TestPreprocessConfluenceTasks(t *testing.T) {
 
 func TestPreprocessConfluenceTasks_NoTasks(t *testing.T) {
 	input := `<p>This is just regular HTML with no tasks</p>`
-	result := preprocessConfluenceTasks(input)
+	result := preprocessStorage(t, input)
 
 	if result != input {
 		t.Errorf("expected unchanged input when no tasks present, got: %s", result)
 	}
 }
+
+func TestPageToMarkdown_StorageElements(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "nested task lists",
+			input: `<ac:task-list><ac:task><ac:task-id>1</ac:task-id><ac:task-status>incomplete</ac:task-status><ac:task-body>parent<ac:task-list><ac:task><ac:task-id>2</ac:task-id><ac:task-status>complete</ac:task-status><ac:task-body>child</ac:task-body></ac:task></ac:task-list></ac:task-body></ac:task></ac:task-list>`,
+			want:  "- [ ] parent\n  - [x] child",
+		},
+		{
+			name:  "code macro with CDATA",
+			input: `<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[if a < b && c {}]]></ac:plain-text-body></ac:structured-macro>`,
+			want:  "```go\nif a < b && c {}\n```",
+		},
+		{
+			name:  "link to URL",
+			input: `<p>See <ac:link><ri:url ri:value="https://example.com" /><ac:plain-text-link-body><![CDATA[the site]]></ac:plain-text-link-body></ac:link>.</p>`,
+			want:  "See [the site](https://example.com).",
+		},
+		{
+			name:  "link to page without body",
+			input: `<p>See <ac:link><ri:page ri:content-title="Runbook" /></ac:link>.</p>`,
+			want:  "See Runbook.",
+		},
+		{
+			name:  "emoticons",
+			input: `<p>Done <ac:emoticon ac:name="tick" /> <ac:emoticon ac:name="blue-star" ac:emoji-shortname=":grinning:" ac:emoji-id="1f600" ac:emoji-fallback="😀" /> <ac:emoticon ac:name="blue-star" ac:emoji-shortname=":woman_technologist:" ac:emoji-id="1f469-200d-1f4bb" ac:emoji-fallback=":woman_technologist:" /> <ac:emoticon ac:name="blue-star" ac:emoji-shortname=":partyparrot:" ac:emoji-id="atlassian-partyparrot" /></p>`,
+			want:  "Done ✅ 😀 👩‍💻 :partyparrot:",
+		},
+		{
+			name:  "date picker",
+			input: `<p>Due <time datetime="2024-03-05" /> at the latest</p>`,
+			want:  "Due 2024-03-05 at the latest",
+		},
+		{
+			name:  "inline comment marker",
+			input: `<p>This <ac:inline-comment-marker ac:ref="abc-123">needs review</ac:inline-comment-marker> soon</p>`,
+			want:  "This needs review soon",
+		},
+		{
+			name:  "placeholder",
+			input: `<p><ac:placeholder>Describe the change here</ac:placeholder>Summary</p>`,
+			want:  "# T\n\nSummary",
+		},
+		{
+			name:  "stray end tag inside element",
+			input: `<p>a</span>b</p>`,
+			want:  "ab",
+		},
+		{
+			name:  "stray end tag at top level",
+			input: `<p>x</p></div><p>y</p>`,
+			want:  "x\n\ny",
+		},
+		{
+			name:  "literal less-than",
+			input: `<p>1 < 2</p>`,
+			want:  "1 < 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &confluence.Page{Title: "T", Body: confluence.Body{Storage: confluence.Storage{Value: tt.input}}}
+			result, err := NewConverter().PageToMarkdown(page, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(result, tt.want) {
+				t.Errorf("expected %q in result, got:\n%s", tt.want, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package markdown
+
+// renderDiagramSourceMacro renders mermaid and plantuml macros as fenced
+// code blocks tagged with the diagram language, which most Markdown
+// renderers draw as diagrams.
+func renderDiagramSourceMacro(m *Macro) (string, error) {
+	source := m.PlainTextBody()
+	if source == "" {
+		return m.converter.renderUnknownMacro(m)
+	}
+
+	lang := "mermaid"
+	if m.Name == "plantuml" {
+		lang = "plantuml"
+	}
+	return m.converter.fencedCode(lang, source), nil
+}
+
+// renderDiagramImageMacro renders draw.io and Gliffy macros as an image of
+// the export each app stores as a page attachment: the SVG export when the
+// page has one, the PNG export otherwise.
+func renderDiagramImageMacro(m *Macro) (string, error) {
+	name := m.Parameters["diagramName"]
+	if name == "" {
+		name = m.Parameters["name"]
+	}
+	if name == "" {
+		return m.converter.renderUnknownMacro(m)
+	}
+
+	export := name + ".png"
+	c := m.converter
+	if c.attachments != nil && m.ctx.page != nil && c.attachments.HasAttachment(m.ctx.page, name+".svg") {
+		export = name + ".svg"
+	}
+	return c.attachmentEmbed(m.ctx, export, name), nil
+}
@@ -0,0 +1,86 @@
+package markdown
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+// fakeAttachments links to attachments by page ID, and reports only files
+// as present.
+type fakeAttachments struct {
+	files []string
+}
+
+func (fakeAttachments) Attachment(page *confluence.Page, filename string) (string, error) {
+	return "attachments/" + page.ID + "/" + filename, nil
+}
+
+func (f fakeAttachments) HasAttachment(page *confluence.Page, filename string) bool {
+	return slices.Contains(f.files, filename)
+}
+
+func TestDiagramMacros(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		input string
+		want  string
+	}{
+		{
+			name: "mermaid",
+			input: `<ac:structured-macro ac:name="mermaid"><ac:plain-text-body><![CDATA[graph TD
+  A --> B]]></ac:plain-text-body></ac:structured-macro>`,
+			want: "```mermaid\ngraph TD\n  A --> B\n```",
+		},
+		{
+			name: "plantuml",
+			input: `<ac:structured-macro ac:name="plantuml"><ac:plain-text-body><![CDATA[@startuml
+Alice -> Bob
+@enduml]]></ac:plain-text-body></ac:structured-macro>`,
+			want: "```plantuml\n@startuml\nAlice -> Bob\n@enduml\n```",
+		},
+		{
+			name:  "drawio without attachments",
+			input: `<ac:structured-macro ac:name="drawio"><ac:parameter ac:name="diagramName">Architecture v2</ac:parameter></ac:structured-macro>`,
+			want:  "![Architecture v2](<Architecture v2.png>)",
+		},
+		{
+			name:  "gliffy with attachments",
+			opts:  []Option{WithAttachments(fakeAttachments{})},
+			input: `<ac:structured-macro ac:name="gliffy"><ac:parameter ac:name="name">flow</ac:parameter></ac:structured-macro>`,
+			want:  "![flow](attachments/1/flow.png)",
+		},
+		{
+			name:  "drawio prefers svg export",
+			opts:  []Option{WithAttachments(fakeAttachments{files: []string{"Architecture.svg"}})},
+			input: `<ac:structured-macro ac:name="drawio"><ac:parameter ac:name="diagramName">Architecture</ac:parameter></ac:structured-macro>`,
+			want:  "![Architecture](attachments/1/Architecture.svg)",
+		},
+		{
+			name:  "attached image without attachments",
+			input: `<p><ac:image><ri:attachment ri:filename="my pic (1).png" /></ac:image></p>`,
+			want:  "![](my%20pic%20%281%29.png)",
+		},
+		{
+			name:  "attached image with attachments",
+			opts:  []Option{WithAttachments(fakeAttachments{})},
+			input: `<p><ac:image ac:alt="Logo"><ri:attachment ri:filename="logo.png" /></ac:image></p>`,
+			want:  "![Logo](attachments/1/logo.png)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := storagePage("1", "ENG", "T", tt.input)
+			got, err := NewConverter(tt.opts...).PageToMarkdown(page, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != "# T\n\n"+tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, "# T\n\n"+tt.want)
+			}
+		})
+	}
+}
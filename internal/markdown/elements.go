@@ -0,0 +1,214 @@
+package markdown
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// transformStorage rewrites Confluence-specific storage elements (tasks,
+// ac:link, ac:image, emoticons, dates) in n's subtree into plain HTML that
+// the Markdown converter understands. It works bottom-up, so nested constructs such as
+// task lists inside tasks are already plain HTML by the time their parent
+// is converted.
+func transformStorage(n *storage.Node) {
+	for _, c := range append([]*storage.Node(nil), n.Children...) {
+		transformStorage(c)
+	}
+
+	switch {
+	case n.Is("ac:task-list"):
+		convertTaskList(n)
+	case n.Is("ac:link"):
+		convertLink(n)
+	case n.Is("ac:image"):
+		convertImage(n)
+	case n.Is("ac:emoticon"):
+		n.ReplaceWith(storage.NewText(emoticon(n)))
+	case n.Is("time"):
+		n.ReplaceWith(storage.NewText(n.Attr("datetime")))
+	case n.Is("ac:inline-comment-marker"):
+		n.Unwrap()
+	case n.Is("ac:placeholder"):
+		// Template instructions, not page content
+		n.Remove()
+	}
+}
+
+// emoticons maps the names of Confluence's built-in emoticons to emoji.
+var emoticons = map[string]string{
+	"smile":        "🙂",
+	"sad":          "🙁",
+	"cheeky":       "😛",
+	"laugh":        "😀",
+	"wink":         "😉",
+	"thumbs-up":    "👍",
+	"thumbs-down":  "👎",
+	"information":  "ℹ️",
+	"tick":         "✅",
+	"cross":        "❌",
+	"warning":      "⚠️",
+	"plus":         "➕",
+	"minus":        "➖",
+	"question":     "❓",
+	"light-on":     "💡",
+	"light-off":    "💡",
+	"yellow-star":  "⭐",
+	"red-star":     "⭐",
+	"green-star":   "⭐",
+	"blue-star":    "⭐",
+	"heart":        "❤️",
+	"broken-heart": "💔",
+}
+
+// emoticon returns the Unicode emoji for an ac:emoticon. Atlassian emoji
+// carry their code points in ac:emoji-id (e.g. "1f642" or
+// "1f469-200d-1f4bb"); custom emoji fall back to their :shortname:.
+func emoticon(n *storage.Node) string {
+	if fallback := n.Attr("ac:emoji-fallback"); fallback != "" && !strings.HasPrefix(fallback, ":") {
+		return fallback
+	}
+	if s, ok := emojiFromID(n.Attr("ac:emoji-id")); ok {
+		return s
+	}
+	// Custom emoji are stored with a generic ac:name such as "blue-star"
+	if shortname := n.Attr("ac:emoji-shortname"); shortname != "" {
+		return shortname
+	}
+	if s, ok := emoticons[n.Attr("ac:name")]; ok {
+		return s
+	}
+	return ":" + n.Attr("ac:name") + ":"
+}
+
+func emojiFromID(id string) (string, bool) {
+	if id == "" {
+		return "", false
+	}
+	var b strings.Builder
+	for _, part := range strings.Split(id, "-") {
+		cp, err := strconv.ParseUint(part, 16, 32)
+		if err != nil || cp < 0x80 {
+			return "", false
+		}
+		b.WriteRune(rune(cp))
+	}
+	return b.String(), true
+}
+
+// convertTaskList turns an ac:task-list into a <ul> whose items start with
+// a "[ ]" or "[x]" checkbox.
+func convertTaskList(list *storage.Node) {
+	ul := storage.NewElement("ul")
+	for _, task := range list.Children {
+		if !task.Is("ac:task") {
+			continue
+		}
+
+		checkbox := "[ ]"
+		if status := task.Child("ac:task-status"); status != nil && strings.TrimSpace(status.Text()) == "complete" {
+			checkbox = "[x]"
+		}
+
+		li := storage.NewElement("li")
+		li.AppendChild(storage.NewText(checkbox + " "))
+		if body := task.Child("ac:task-body"); body != nil {
+			// Drop placeholder spans
+			for _, wrapper := range body.FindAll("span") {
+				wrapper.Unwrap()
+			}
+			trimText(body)
+			for _, c := range append([]*storage.Node(nil), body.Children...) {
+				li.AppendChild(c)
+			}
+		}
+		// Nested task lists sit alongside the body and were already converted
+		for _, c := range task.Children {
+			if c.Is("ul") {
+				li.AppendChild(c)
+			}
+		}
+
+		ul.AppendChild(li)
+	}
+	list.ReplaceWith(ul)
+}
+
+// convertLink turns an ac:link to a URL, page, attachment or anchor into
+// an <a> element, or plain text when there is no usable target.
+func convertLink(link *storage.Node) {
+	href := ""
+	if anchor := link.Attr("ac:anchor"); anchor != "" {
+		href = "#" + anchor
+	}
+
+	var fallback string
+	switch {
+	case link.Child("ri:url") != nil:
+		href = link.Child("ri:url").Attr("ri:value")
+		fallback = href
+	case link.Child("ri:page") != nil:
+		fallback = link.Child("ri:page").Attr("ri:content-title")
+	case link.Child("ri:attachment") != nil:
+		fallback = link.Child("ri:attachment").Attr("ri:filename")
+	}
+
+	var body []*storage.Node
+	if b := link.Child("ac:link-body"); b != nil {
+		body = b.Children
+	} else if b := link.Child("ac:plain-text-link-body"); b != nil {
+		body = []*storage.Node{storage.NewText(b.Text())}
+	} else if fallback != "" {
+		body = []*storage.Node{storage.NewText(fallback)}
+	} else if href != "" {
+		body = []*storage.Node{storage.NewText(strings.TrimPrefix(href, "#"))}
+	}
+
+	if href == "" {
+		link.ReplaceWith(body...)
+		return
+	}
+	a := storage.NewElement("a", storage.Attr{Name: "href", Value: href})
+	for _, c := range append([]*storage.Node(nil), body...) {
+		a.AppendChild(c)
+	}
+	link.ReplaceWith(a)
+}
+
+// convertImage turns an ac:image referencing a URL or attachment into an
+// <img> element.
+func convertImage(image *storage.Node) {
+	src := ""
+	if u := image.Child("ri:url"); u != nil {
+		src = u.Attr("ri:value")
+	} else if a := image.Child("ri:attachment"); a != nil {
+		// Escaped like downloaded attachments' links, as the file name
+		// may hold spaces or parentheses
+		src = url.PathEscape(a.Attr("ri:filename"))
+	}
+	if src == "" {
+		image.Remove()
+		return
+	}
+
+	img := storage.NewElement("img", storage.Attr{Name: "src", Value: src})
+	if alt := image.Attr("ac:alt"); alt != "" {
+		img.Attrs = append(img.Attrs, storage.Attr{Name: "alt", Value: alt})
+	}
+	image.ReplaceWith(img)
+}
+
+// trimText strips leading and trailing whitespace from n's content.
+func trimText(n *storage.Node) {
+	if len(n.Children) == 0 {
+		return
+	}
+	if first := n.Children[0]; first.Type == storage.TextNode {
+		first.Data = strings.TrimLeft(first.Data, " \t\r\n")
+	}
+	if last := n.Children[len(n.Children)-1]; last.Type == storage.TextNode {
+		last.Data = strings.TrimRight(last.Data, " \t\r\n")
+	}
+}
@@ -0,0 +1,455 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// AdmonitionStyle selects the syntax info, note, tip and warning panels are
+// written in.
+type AdmonitionStyle string
+
+const (
+	// AdmonitionGFM writes GitHub alerts: "> [!NOTE]".
+	AdmonitionGFM AdmonitionStyle = "gfm"
+	// AdmonitionBlockquote writes a blockquote led by the panel type in
+	// bold, for renderers without admonitions.
+	AdmonitionBlockquote AdmonitionStyle = "blockquote"
+	// AdmonitionObsidian writes Obsidian callouts: "> [!note] Title".
+	AdmonitionObsidian AdmonitionStyle = "obsidian"
+	// AdmonitionMkDocs writes Python-Markdown admonitions: `!!! note "Title"`.
+	AdmonitionMkDocs AdmonitionStyle = "mkdocs"
+	// AdmonitionDocusaurus writes Docusaurus admonitions: ":::note[Title]".
+	AdmonitionDocusaurus AdmonitionStyle = "docusaurus"
+)
+
+// FrontMatterStyle selects the extra fields a flavor adds to front matter.
+type FrontMatterStyle string
+
+const (
+	// FrontMatterBasic writes only the fields push needs.
+	FrontMatterBasic FrontMatterStyle = "basic"
+	// FrontMatterHugo adds Hugo's date and lastmod.
+	FrontMatterHugo FrontMatterStyle = "hugo"
+	// FrontMatterDocusaurus adds Docusaurus' last_update.
+	FrontMatterDocusaurus FrontMatterStyle = "docusaurus"
+)
+
+// Flavor describes the Markdown dialect a target renderer expects.
+type Flavor struct {
+	Name string
+	// TitleHeading writes the page title as a level 1 heading. Site
+	// generators that show the front matter title leave it out.
+	TitleHeading bool
+	// HeadingStyle is "atx" for # headings or "setext" for underlined
+	// level 1 and 2 headings.
+	HeadingStyle string
+	// Fence is the code fence, ``` or ~~~.
+	Fence string
+	// BulletMarker is the bullet list marker: -, * or +.
+	BulletMarker string
+	// ListIndent is the number of spaces each nested list level is
+	// indented by, at least the width of the item marker. Python-Markdown
+	// needs 4.
+	ListIndent  int
+	Admonitions AdmonitionStyle
+	// WikiLinks writes links to other pages as [[Title|text]].
+	WikiLinks   bool
+	FrontMatter FrontMatterStyle
+	// MDX makes the output valid MDX: braces and stray "<" are escaped,
+	// HTML comments become JSX comments and void elements are self-closed.
+	MDX bool
+}
+
+// Flavors are the built-in output profiles, by name.
+var Flavors = map[string]Flavor{
+	"gfm": {
+		Name: "gfm", HeadingStyle: "atx", TitleHeading: true, Fence: "```", BulletMarker: "-", ListIndent: 2,
+		Admonitions: AdmonitionGFM, FrontMatter: FrontMatterBasic,
+	},
+	"commonmark": {
+		Name: "commonmark", HeadingStyle: "atx", TitleHeading: true, Fence: "```", BulletMarker: "-", ListIndent: 2,
+		Admonitions: AdmonitionBlockquote, FrontMatter: FrontMatterBasic,
+	},
+	"obsidian": {
+		Name: "obsidian", HeadingStyle: "atx", TitleHeading: true, Fence: "```", BulletMarker: "-", ListIndent: 2,
+		Admonitions: AdmonitionObsidian, WikiLinks: true, FrontMatter: FrontMatterBasic,
+	},
+	"hugo": {
+		Name: "hugo", HeadingStyle: "atx", TitleHeading: false, Fence: "```", BulletMarker: "-", ListIndent: 2,
+		Admonitions: AdmonitionGFM, FrontMatter: FrontMatterHugo,
+	},
+	"mkdocs": {
+		Name: "mkdocs", HeadingStyle: "atx", TitleHeading: true, Fence: "```", BulletMarker: "-", ListIndent: 4,
+		Admonitions: AdmonitionMkDocs, FrontMatter: FrontMatterBasic,
+	},
+	"docusaurus": {
+		Name: "docusaurus", HeadingStyle: "atx", TitleHeading: true, Fence: "```", BulletMarker: "-", ListIndent: 2,
+		Admonitions: AdmonitionDocusaurus, FrontMatter: FrontMatterDocusaurus, MDX: true,
+	},
+}
+
+// ParseFlavor looks up a --flavor flag value.
+func ParseFlavor(name string) (Flavor, error) {
+	if f, ok := Flavors[strings.ToLower(name)]; ok {
+		return f, nil
+	}
+	var names []string
+	for n := range Flavors {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return Flavor{}, fmt.Errorf("unknown flavor %q (expected one of %s)", name, strings.Join(names, ", "))
+}
+
+// WithFlavor sets the Markdown dialect to write.
+func WithFlavor(f Flavor) Option {
+	return func(c *Converter) {
+		c.flavor = f
+	}
+}
+
+// Flavor returns the Markdown dialect the converter writes.
+func (c *Converter) Flavor() Flavor {
+	return c.flavor
+}
+
+// markdownOptions configures the HTML converter for the flavor.
+func (f Flavor) markdownOptions() *md.Options {
+	return &md.Options{
+		HeadingStyle:     f.HeadingStyle,
+		HorizontalRule:   "---",
+		BulletListMarker: f.BulletMarker,
+		CodeBlockStyle:   "fenced",
+		Fence:            f.Fence,
+	}
+}
+
+// listPrefixAttr is where the HTML converter keeps each list item's marker
+// before rendering it. Nested lists are indented by the marker's width.
+const listPrefixAttr = "data-converter-list-prefix"
+
+// padListPrefixes widens the list item markers the HTML converter assigned
+// to the flavor's list indent, so nested lists are indented by it.
+func (f Flavor) padListPrefixes(selec *goquery.Selection) {
+	selec.Find("li").Each(func(_ int, li *goquery.Selection) {
+		if prefix := li.AttrOr(listPrefixAttr, ""); prefix != "" {
+			li.SetAttr(listPrefixAttr, f.listMarker(prefix))
+		}
+	})
+}
+
+// listMarker pads a list item marker, such as "- " or "1. ", to the
+// flavor's list indent.
+func (f Flavor) listMarker(marker string) string {
+	if pad := f.ListIndent - len(marker); pad > 0 {
+		marker += strings.Repeat(" ", pad)
+	}
+	return marker
+}
+
+// heading writes a heading in the flavor's heading style. Setext only has
+// levels 1 and 2; deeper headings are ATX in either style.
+func (f Flavor) heading(level int, text string) string {
+	if f.HeadingStyle == "setext" && level <= 2 {
+		underline := "="
+		if level == 2 {
+			underline = "-"
+		}
+		return text + "\n" + strings.Repeat(underline, max(3, len([]rune(text))))
+	}
+	return strings.Repeat("#", level) + " " + text
+}
+
+// NewFrontMatter builds the front matter for page in the flavor's layout.
+// baseURL is the Confluence site URL used to make the page link absolute.
+func (f Flavor) NewFrontMatter(page *confluence.Page, baseURL string) FrontMatter {
+	fm := NewFrontMatter(page, baseURL)
+	switch f.FrontMatter {
+	case FrontMatterHugo:
+		fm.Date = formatDate(page.History.CreatedDate)
+		fm.LastMod = formatDate(page.Version.When)
+	case FrontMatterDocusaurus:
+		if !page.Version.When.IsZero() {
+			fm.LastUpdate = &LastUpdate{Date: formatDate(page.Version.When), Author: page.Version.By.Name()}
+		}
+	}
+	return fm
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// admonitionTypes maps GFM alert types to each style's admonition types.
+var admonitionTypes = map[AdmonitionStyle]map[string]string{
+	AdmonitionMkDocs: {
+		"NOTE": "note", "TIP": "tip", "IMPORTANT": "warning", "WARNING": "danger", "CAUTION": "danger",
+	},
+	AdmonitionDocusaurus: {
+		"NOTE": "info", "TIP": "tip", "IMPORTANT": "warning", "WARNING": "danger", "CAUTION": "danger",
+	},
+}
+
+// admonition renders a panel's body as an admonition of the given GFM
+// alert type, or as a plain blockquote when alert is empty.
+func (c *Converter) admonition(alert, title, body string) string {
+	style := c.flavor.Admonitions
+	if alert == "" {
+		style = AdmonitionBlockquote
+	}
+
+	switch style {
+	case AdmonitionMkDocs:
+		header := "!!! " + admonitionTypes[style][alert]
+		if title != "" {
+			header += ` "` + strings.ReplaceAll(title, `"`, `\"`) + `"`
+		}
+		if body == "" {
+			return header
+		}
+		return header + "\n\n" + indent(body, "    ")
+
+	case AdmonitionDocusaurus:
+		header := ":::" + admonitionTypes[style][alert]
+		if title != "" {
+			header += "[" + title + "]"
+		}
+		return header + "\n\n" + body + "\n\n:::"
+
+	case AdmonitionObsidian:
+		header := "[!" + strings.ToLower(alert) + "]"
+		if title != "" {
+			header += " " + title
+		}
+		if body == "" {
+			return quote(header)
+		}
+		return quote(header + "\n" + body)
+	}
+
+	var parts []string
+	if style == AdmonitionGFM {
+		parts = append(parts, "[!"+alert+"]")
+	}
+	label := title
+	if style == AdmonitionBlockquote && alert != "" {
+		label = alertLabel(alert)
+		if title != "" {
+			label += ": " + title
+		}
+	}
+	if label != "" {
+		parts = append(parts, "**"+label+"**")
+	}
+	if body != "" {
+		if label != "" {
+			parts = append(parts, "")
+		}
+		parts = append(parts, body)
+	}
+	return quote(strings.Join(parts, "\n"))
+}
+
+// alertLabel turns a GFM alert type into a label: "NOTE" becomes "Note".
+func alertLabel(alert string) string {
+	return alert[:1] + strings.ToLower(alert[1:])
+}
+
+// convertWikiLinks replaces links to other pages in n's subtree with
+// placeholders for Obsidian-style [[Title|text]] links.
+func (c *Converter) convertWikiLinks(n *storage.Node, ph *placeholders) {
+	for _, link := range n.FindAll("ac:link") {
+		ref := link.Child("ri:page")
+		if ref == nil {
+			continue
+		}
+		title := ref.Attr("ri:content-title")
+		if title == "" {
+			continue
+		}
+
+		// Links resolve to the note's file name, which can't hold every
+		// character a title can
+		target := FileName(title)
+		if anchor := link.Attr("ac:anchor"); anchor != "" {
+			target += "#" + anchor
+		}
+		text := ""
+		if b := link.Child("ac:link-body"); b != nil {
+			text = strings.TrimSpace(b.Text())
+		} else if b := link.Child("ac:plain-text-link-body"); b != nil {
+			text = strings.TrimSpace(b.Text())
+		}
+		if text == "" && target != title {
+			text = title
+		}
+
+		wikilink := "[[" + target + "]]"
+		if text != "" && text != target {
+			wikilink = "[[" + target + "|" + text + "]]"
+		}
+		link.ReplaceWith(storage.NewText(ph.add(wikilink, true)))
+	}
+}
+
+// FileName makes a page title safe to use as a file name, and as the
+// target of a wikilink to that file.
+func FileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', '#', '^', '[', ']':
+			return '-'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, title)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "untitled"
+	}
+	return name
+}
+
+var (
+	htmlComment = regexp.MustCompile(`(?s)<!--(.*?)-->`)
+	voidElement = regexp.MustCompile(`<(br|hr|img)(\s[^<>]*?)?\s*/?>`)
+	// htmlTag matches an HTML comment, start tag or end tag at the start
+	// of a string.
+	htmlTag = regexp.MustCompile(`^(?s:<!--.*?-->|</?[A-Za-z][\w.:-]*(?:\s[^<>]*)?/?>)`)
+)
+
+// toMDX rewrites the Markdown and HTML in md that MDX rejects, leaving
+// fenced code blocks alone: braces and a "<" that doesn't start a tag are
+// escaped, comments become JSX comments and void elements are self-closed.
+func toMDX(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	var out, prose []string
+	flush := func() {
+		if len(prose) == 0 {
+			return
+		}
+		text := escapeMDX(strings.Join(prose, "\n"))
+		text = htmlComment.ReplaceAllStringFunc(text, func(s string) string {
+			inner := htmlComment.FindStringSubmatch(s)[1]
+			return "{/*" + strings.ReplaceAll(inner, "*/", "* /") + "*/}"
+		})
+		text = voidElement.ReplaceAllString(text, "<$1$2 />")
+		out = append(out, strings.Split(text, "\n")...)
+		prose = nil
+	}
+
+	fence := ""
+	for _, line := range lines {
+		switch {
+		case fence != "":
+			out = append(out, line)
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+		case fenceOpening.MatchString(line):
+			flush()
+			fence = fenceOpening.FindStringSubmatch(line)[1]
+			out = append(out, line)
+		default:
+			prose = append(prose, line)
+		}
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// escapeMDX backslash-escapes the braces in prose, which MDX reads as
+// JavaScript expressions, and each "<" that MDX would read as the start of
+// JSX but isn't an HTML tag, as in "<3". HTML tags and comments, code
+// spans, math and existing escapes are left alone.
+func escapeMDX(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		switch c := text[i]; c {
+		case '\\':
+			n := min(2, len(text)-i)
+			b.WriteString(text[i : i+n])
+			i += n
+		case '`', '$':
+			n := literalSpan(text[i:])
+			b.WriteString(text[i : i+n])
+			i += n
+		case '<':
+			if tag := htmlTag.FindString(text[i:]); tag != "" {
+				b.WriteString(tag)
+				i += len(tag)
+				continue
+			}
+			if i+1 < len(text) && !unicode.IsSpace(rune(text[i+1])) {
+				b.WriteString(`\`)
+			}
+			b.WriteByte(c)
+			i++
+		case '{', '}':
+			b.WriteString(`\` + string(c))
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// literalSpan returns the length of the code span or math at the start of
+// text, which starts with a backtick or "$". Without a closing delimiter,
+// it returns the length of the opening run, which is then plain text.
+// Inline math follows the usual "$...$" rule: no space inside either
+// delimiter and no digit after the closing one, so prices aren't math.
+func literalSpan(text string) int {
+	c := text[0]
+	run := len(text) - len(strings.TrimLeft(text, string(c)))
+	switch {
+	case c == '`':
+		for j := run; j < len(text); {
+			if text[j] != '`' {
+				j++
+				continue
+			}
+			k := j
+			for k < len(text) && text[k] == '`' {
+				k++
+			}
+			if k-j == run {
+				return k
+			}
+			j = k
+		}
+	case run >= 2:
+		if end := strings.Index(text[2:], "$$"); end >= 0 {
+			return end + 4
+		}
+	default:
+		for end := 1; end < len(text); end++ {
+			if text[end] != '$' {
+				continue
+			}
+			if end > 1 && !unicode.IsSpace(rune(text[1])) && !unicode.IsSpace(rune(text[end-1])) &&
+				(end+1 == len(text) || !unicode.IsDigit(rune(text[end+1]))) {
+				return end + 1
+			}
+			break
+		}
+	}
+	return run
+}
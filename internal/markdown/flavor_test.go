@@ -0,0 +1,232 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+func TestFlavor_Admonitions(t *testing.T) {
+	input := `<ac:structured-macro ac:name="warning"><ac:parameter ac:name="title">Careful</ac:parameter><ac:rich-text-body><p>Backups first.</p></ac:rich-text-body></ac:structured-macro>`
+
+	tests := []struct {
+		flavor string
+		want   string
+	}{
+		{"gfm", "> [!WARNING]\n> **Careful**\n>\n> Backups first."},
+		{"commonmark", "> **Warning: Careful**\n>\n> Backups first."},
+		{"obsidian", "> [!warning] Careful\n> Backups first."},
+		{"mkdocs", "!!! danger \"Careful\"\n\n    Backups first."},
+		{"docusaurus", ":::danger[Careful]\n\nBackups first.\n\n:::"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flavor, func(t *testing.T) {
+			got := convertStorage(t, NewConverter(WithFlavor(Flavors[tt.flavor])), input)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlavor_WikiLinks(t *testing.T) {
+	input := `<p>See <ac:link><ri:page ri:content-title="Runbook" /></ac:link>, <ac:link ac:anchor="Rollback"><ri:page ri:content-title="Runbook" /><ac:plain-text-link-body><![CDATA[how to roll back]]></ac:plain-text-link-body></ac:link> and <ac:link><ri:url ri:value="https://example.com" /><ac:plain-text-link-body><![CDATA[the site]]></ac:plain-text-link-body></ac:link>.</p>`
+
+	got := convertStorage(t, NewConverter(WithFlavor(Flavors["obsidian"])), input)
+	want := "See [[Runbook]], [[Runbook#Rollback|how to roll back]] and [the site](https://example.com)."
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = convertStorage(t, NewConverter(), input)
+	if strings.Contains(got, "[[") {
+		t.Errorf("expected no wikilinks without the obsidian flavor, got:\n%s", got)
+	}
+}
+
+func TestFlavor_CodeAndTitle(t *testing.T) {
+	f := Flavors["gfm"]
+	f.Fence = "~~~"
+	f.BulletMarker = "*"
+	f.HeadingStyle = "setext"
+	c := NewConverter(WithFlavor(f))
+
+	page := &confluence.Page{Title: "T", Body: confluence.Body{Storage: confluence.Storage{
+		Value: `<h2>Setup</h2><ul><li>one</li></ul><pre>plain</pre><ac:structured-macro ac:name="code"><ac:plain-text-body><![CDATA[x := 1]]></ac:plain-text-body></ac:structured-macro>`,
+	}}}
+	got, err := c.PageToMarkdown(page, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "T\n===\n\nSetup\n-----\n\n* one\n\n~~~\nplain\n~~~\n\n~~~\nx := 1\n~~~"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFlavor_ListIndent(t *testing.T) {
+	input := `<ul><li>one<ol><li>two</li></ol></li></ul>` +
+		`<ac:structured-macro ac:name="info"><ac:rich-text-body><ul><li>a<ul><li>b</li></ul></li></ul></ac:rich-text-body></ac:structured-macro>` +
+		`<ac:structured-macro ac:name="toc" /><h1>Setup</h1><h2>Install</h2>`
+
+	tests := []struct {
+		flavor string
+		want   string
+	}{
+		{"gfm", "- one\n  1. two\n\n> [!NOTE]\n> - a\n>   - b\n\n- [Setup](#setup)\n  - [Install](#install)"},
+		{"mkdocs", "-   one\n    1.  two\n\n!!! note\n\n    -   a\n        -   b\n\n-   [Setup](#setup)\n    -   [Install](#install)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flavor, func(t *testing.T) {
+			page := storagePage("1", "ENG", "T", input)
+			got, err := NewConverter(WithFlavor(Flavors[tt.flavor])).PageToMarkdown(page, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := "# T\n\n" + tt.want + "\n\n# Setup\n\n## Install"
+			if got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestToMDX(t *testing.T) {
+	input := "Text <!-- not included --> and<br>line\n\n```html\n<!-- kept --><br>\n```\n\n<img src=\"a.png\">"
+	want := "Text {/* not included */} and<br />line\n\n```html\n<!-- kept --><br>\n```\n\n<img src=\"a.png\" />"
+	if got := toMDX(input); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestToMDX_Escapes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"braces", "Hello {name}, see {{x}}", `Hello \{name\}, see \{\{x\}\}`},
+		{"less-than", "a <3 b < c <> d<", `a \<3 b < c \<> d<`},
+		{"tags kept", `<details><summary>{x}</summary></details><a id="y"></a>`, `<details><summary>\{x\}</summary></details><a id="y"></a>`},
+		{"code span", "Use `{name}` or ``a ` {b}`` then {c}", "Use `{name}` or ``a ` {b}`` then \\{c\\}"},
+		{"unclosed code span", "a ` {b}", "a ` \\{b\\}"},
+		{"math", `$\frac{a}{b}$ and $$x^{2}$$`, `$\frac{a}{b}$ and $$x^{2}$$`},
+		{"prices", "$5 {x} $6", `$5 \{x\} $6`},
+		{"existing escapes", `\{ \\{`, `\{ \\\{`},
+		{"comment", "<!-- {x} -->", "{/* {x} */}"},
+		{"fenced code", "```\n{x} <3\n```", "```\n{x} <3\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toMDX(tt.input); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlavor_NewFrontMatter(t *testing.T) {
+	page := &confluence.Page{
+		ID:      "1",
+		Title:   "Runbook",
+		Version: confluence.Version{Number: 3, When: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), By: confluence.User{DisplayName: "Jane Doe"}},
+		History: confluence.History{CreatedDate: time.Date(2023, 1, 2, 9, 0, 0, 0, time.UTC)},
+	}
+
+	hugo := Flavors["hugo"].NewFrontMatter(page, "")
+	if hugo.Date != "2023-01-02T09:00:00Z" || hugo.LastMod != "2024-03-05T10:00:00Z" {
+		t.Errorf("unexpected hugo dates: %+v", hugo)
+	}
+
+	docusaurus := Flavors["docusaurus"].NewFrontMatter(page, "")
+	if docusaurus.LastUpdate == nil || docusaurus.LastUpdate.Author != "Jane Doe" {
+		t.Errorf("unexpected docusaurus last_update: %+v", docusaurus.LastUpdate)
+	}
+
+	basic := Flavors["gfm"].NewFrontMatter(page, "")
+	if !reflect.DeepEqual(basic, NewFrontMatter(page, "")) {
+		t.Errorf("expected gfm front matter to match the default, got %+v", basic)
+	}
+}
+
+func TestParseFlavor(t *testing.T) {
+	if f, err := ParseFlavor("MkDocs"); err != nil || f.Admonitions != AdmonitionMkDocs {
+		t.Errorf("expected mkdocs flavor, got %+v, %v", f, err)
+	}
+	if _, err := ParseFlavor("asciidoc"); err == nil {
+		t.Error("expected error for unknown flavor")
+	}
+}
+
+func TestFlavor_ObsidianAttachments(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		input string
+		want  string
+	}{
+		{
+			name:  "image embed",
+			input: `<p><ac:image ac:width="300"><ri:attachment ri:filename="logo.png" /></ac:image></p>`,
+			want:  "![[logo.png|300]]",
+		},
+		{
+			name:  "downloaded image embed",
+			opts:  []Option{WithAttachments(fakeAttachments{})},
+			input: `<p><ac:image><ri:attachment ri:filename="my logo.png" /></ac:image></p>`,
+			want:  "![[my logo.png]]",
+		},
+		{
+			name:  "link to attachment",
+			input: `<p><ac:link><ri:attachment ri:filename="spec.pdf" /><ac:plain-text-link-body><![CDATA[the spec]]></ac:plain-text-link-body></ac:link></p>`,
+			want:  "[[spec.pdf|the spec]]",
+		},
+		{
+			name:  "diagram",
+			input: `<ac:structured-macro ac:name="drawio"><ac:parameter ac:name="diagramName">flow</ac:parameter></ac:structured-macro>`,
+			want:  "![[flow.png]]",
+		},
+		{
+			name:  "link to page with unsafe title",
+			input: `<p><ac:link><ri:page ri:content-title="Q&amp;A: Ops" /></ac:link></p>`,
+			want:  "[[Q&A- Ops|Q&A: Ops]]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithFlavor(Flavors["obsidian"])}, tt.opts...)
+			page := storagePage("1", "ENG", "T", tt.input)
+			got, err := NewConverter(opts...).PageToMarkdown(page, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != "# T\n\n"+tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, "# T\n\n"+tt.want)
+			}
+		})
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Runbook", "Runbook"},
+		{"CI/CD: Setup?", "CI-CD- Setup-"},
+		{"Issue #12 [draft]", "Issue -12 -draft-"},
+		{"...", "untitled"},
+	}
+	for _, tt := range tests {
+		if got := FileName(tt.title); got != tt.want {
+			t.Errorf("FileName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"gopkg.in/yaml.v3"
+)
+
+const frontMatterDelimiter = "---"
+
+// FrontMatter is the YAML header written by "fetch --front-matter". It
+// records where a Markdown file came from so it can be pushed back.
+type FrontMatter struct {
+	ID      string `yaml:"id"`
+	Title   string `yaml:"title"`
+	Space   string `yaml:"space,omitempty"`
+	Version int    `yaml:"version"`
+	URL     string `yaml:"url,omitempty"`
+	// Tags holds the page's labels, for tools like Obsidian that read them.
+	Tags []string `yaml:"tags,omitempty"`
+
+	// Fields added by some flavors for their site generator.
+	Date       string      `yaml:"date,omitempty"`
+	LastMod    string      `yaml:"lastmod,omitempty"`
+	LastUpdate *LastUpdate `yaml:"last_update,omitempty"`
+	// Weight orders pages in Hugo menus, following Confluence's page order.
+	Weight int `yaml:"weight,omitempty"`
+}
+
+// LastUpdate is Docusaurus' record of when and by whom a page last changed.
+type LastUpdate struct {
+	Date   string `yaml:"date"`
+	Author string `yaml:"author,omitempty"`
+}
+
+// NewFrontMatter builds front matter describing page. baseURL is the
+// Confluence site URL used to make the page link absolute.
+func NewFrontMatter(page *confluence.Page, baseURL string) FrontMatter {
+	fm := FrontMatter{
+		ID:      page.ID,
+		Title:   page.Title,
+		Space:   page.Space.Key,
+		Version: page.Version.Number,
+	}
+	if page.Links.WebUI != "" {
+		fm.URL = baseURL + page.Links.WebUI
+	}
+	return fm
+}
+
+// Render returns the front matter as a YAML block delimited by "---" lines.
+func (f FrontMatter) Render() (string, error) {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return "", fmt.Errorf("encoding front matter: %w", err)
+	}
+	return frontMatterDelimiter + "\n" + string(data) + frontMatterDelimiter + "\n\n", nil
+}
+
+// SplitFrontMatter separates a leading YAML front matter block from the
+// rest of src. It returns nil front matter and src unchanged when the
+// document has none.
+func SplitFrontMatter(src []byte) (*FrontMatter, []byte, error) {
+	opening := []byte(frontMatterDelimiter + "\n")
+	if !bytes.HasPrefix(src, opening) {
+		return nil, src, nil
+	}
+
+	rest := src[len(opening):]
+	closing := []byte("\n" + frontMatterDelimiter + "\n")
+	end := bytes.Index(rest, closing)
+	if end < 0 {
+		if bytes.HasSuffix(rest, []byte("\n"+frontMatterDelimiter)) {
+			end = len(rest) - len(frontMatterDelimiter) - 1
+		} else {
+			return nil, nil, fmt.Errorf("unterminated front matter")
+		}
+	}
+
+	var fm FrontMatter
+	if err := yaml.Unmarshal(rest[:end], &fm); err != nil {
+		return nil, nil, fmt.Errorf("parsing front matter: %w", err)
+	}
+
+	body := rest[min(end+len(closing), len(rest)):]
+	return &fm, bytes.TrimLeft(body, "\n"), nil
+}
@@ -0,0 +1,53 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFrontMatterRoundTrip(t *testing.T) {
+	fm := FrontMatter{
+		ID:      "123456",
+		Title:   "Runbook: Deploys",
+		Space:   "ENG",
+		Version: 7,
+		URL:     "https://example.atlassian.net/wiki/spaces/ENG/pages/123456",
+		Tags:    []string{"runbook", "ops"},
+	}
+
+	rendered, err := fm.Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, body, err := SplitFrontMatter([]byte(rendered + "# Runbook: Deploys\n\nBody\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil || !reflect.DeepEqual(*got, fm) {
+		t.Errorf("expected %+v, got %+v", fm, got)
+	}
+	if string(body) != "# Runbook: Deploys\n\nBody\n" {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
+
+func TestSplitFrontMatter_None(t *testing.T) {
+	input := []byte("# Title\n\n---\n\nNot front matter\n")
+	fm, body, err := SplitFrontMatter(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fm != nil {
+		t.Errorf("expected no front matter, got %+v", fm)
+	}
+	if string(body) != string(input) {
+		t.Errorf("expected body unchanged, got %q", body)
+	}
+}
+
+func TestSplitFrontMatter_Unterminated(t *testing.T) {
+	if _, _, err := SplitFrontMatter([]byte("---\nid: \"1\"\n")); err == nil {
+		t.Error("expected error for unterminated front matter")
+	}
+}
@@ -0,0 +1,161 @@
+package markdown
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// PageResolver looks up the pages referenced by include and
+// excerpt-include macros. *confluence.Client implements it.
+type PageResolver interface {
+	GetPageByTitle(spaceKey, title string) (*confluence.Page, error)
+}
+
+// includeResolver fetches included pages, caching them for the lifetime of
+// the Converter so a page included several times is fetched once.
+type includeResolver struct {
+	pages    PageResolver
+	maxDepth int
+	cache    map[string]*confluence.Page
+}
+
+// WithIncludes expands include and excerpt-include macros inline by
+// fetching the referenced pages through r. Included pages may include
+// others, up to maxDepth levels deep.
+func WithIncludes(r PageResolver, maxDepth int) Option {
+	return func(c *Converter) {
+		c.includes = &includeResolver{
+			pages:    r,
+			maxDepth: maxDepth,
+			cache:    map[string]*confluence.Page{},
+		}
+	}
+}
+
+func (r *includeResolver) page(spaceKey, title string) (*confluence.Page, error) {
+	key := spaceKey + "\x00" + title
+	if page, ok := r.cache[key]; ok {
+		return page, nil
+	}
+	page, err := r.pages.GetPageByTitle(spaceKey, title)
+	if err != nil {
+		return nil, err
+	}
+	r.cache[key] = page
+	return page, nil
+}
+
+// renderIncludeMacro renders an include macro as the body of the page it
+// references.
+func renderIncludeMacro(m *Macro) (string, error) {
+	return m.converter.renderInclude(m, false)
+}
+
+// renderExcerptIncludeMacro renders an excerpt-include macro as the excerpt
+// macro on the page it references.
+func renderExcerptIncludeMacro(m *Macro) (string, error) {
+	return m.converter.renderInclude(m, true)
+}
+
+// renderExcerptMacro renders an excerpt macro's body in place, unless the
+// excerpt is marked hidden on its own page.
+func renderExcerptMacro(m *Macro) (string, error) {
+	if m.Parameters["hidden"] == "true" {
+		return "", nil
+	}
+	return m.Body()
+}
+
+func (c *Converter) renderInclude(m *Macro, excerpt bool) (string, error) {
+	if c.includes == nil {
+		return c.renderUnknownMacro(m)
+	}
+
+	spaceKey, title := includeTarget(m)
+	if title == "" {
+		return includeComment("include without a page reference"), nil
+	}
+
+	if len(m.ctx.includeChain) >= c.includes.maxDepth {
+		return includeComment(fmt.Sprintf("not included %q: include depth limit %d reached", title, c.includes.maxDepth)), nil
+	}
+
+	page, err := c.includes.page(spaceKey, title)
+	if err != nil {
+		return includeComment(fmt.Sprintf("could not include %q: %v", title, err)), nil
+	}
+
+	chain := append(slices.Clone(m.ctx.includeChain), pageID(m.Page))
+	if slices.Contains(chain, page.ID) {
+		return includeComment(fmt.Sprintf("not included %q: include cycle", title)), nil
+	}
+
+	doc, err := storage.Parse(page.Body.Storage.Value)
+	if err != nil {
+		return "", fmt.Errorf("parsing included page %q: %w", title, err)
+	}
+
+	if excerpt {
+		name := m.Parameters["name"]
+		body := findExcerpt(doc, name)
+		if body == nil {
+			return includeComment(fmt.Sprintf("no excerpt on page %q", title)), nil
+		}
+		doc = body
+	}
+	return c.nodesToMarkdown(doc.Children, renderContext{page: page, from: m.ctx.from, includeChain: chain, headings: collectHeadings(doc)})
+}
+
+// includeTarget returns the space key and title of the page an include
+// macro references. Without an explicit space, the page is looked up in
+// the including page's space.
+func includeTarget(m *Macro) (spaceKey, title string) {
+	ref := m.Node.Find("ri:page")
+	if ref == nil {
+		return "", ""
+	}
+	spaceKey = ref.Attr("ri:space-key")
+	if spaceKey == "" && m.Page != nil {
+		spaceKey = m.Page.Space.Key
+	}
+	return spaceKey, ref.Attr("ri:content-title")
+}
+
+// findExcerpt returns the rich-text body of the excerpt macro with the
+// given name, or of the first excerpt macro when name is empty.
+func findExcerpt(doc *storage.Node, name string) *storage.Node {
+	for _, n := range doc.FindAll("ac:structured-macro") {
+		if n.Attr("ac:name") != "excerpt" {
+			continue
+		}
+		if name != "" && excerptName(n) != name {
+			continue
+		}
+		return n.Child("ac:rich-text-body")
+	}
+	return nil
+}
+
+func excerptName(n *storage.Node) string {
+	for _, p := range n.Children {
+		if p.Is("ac:parameter") && p.Attr("ac:name") == "name" {
+			return strings.TrimSpace(p.Text())
+		}
+	}
+	return ""
+}
+
+func pageID(page *confluence.Page) string {
+	if page == nil {
+		return ""
+	}
+	return page.ID
+}
+
+func includeComment(msg string) string {
+	return "<!-- " + strings.ReplaceAll(msg, "--", "- -") + " -->"
+}
@@ -0,0 +1,121 @@
+package markdown
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+// fakePages is a PageResolver over an in-memory set of pages keyed by
+// "SPACE/Title".
+type fakePages struct {
+	pages   map[string]*confluence.Page
+	fetches int
+}
+
+func (f *fakePages) GetPageByTitle(spaceKey, title string) (*confluence.Page, error) {
+	f.fetches++
+	page, ok := f.pages[spaceKey+"/"+title]
+	if !ok {
+		return nil, fmt.Errorf("page %q not found in space %s", title, spaceKey)
+	}
+	return page, nil
+}
+
+func storagePage(id, space, title, body string) *confluence.Page {
+	return &confluence.Page{
+		ID:    id,
+		Title: title,
+		Space: confluence.Space{Key: space},
+		Body:  confluence.Body{Storage: confluence.Storage{Value: body}},
+	}
+}
+
+func includeMacro(name, space, title string) string {
+	spaceAttr := ""
+	if space != "" {
+		spaceAttr = ` ri:space-key="` + space + `"`
+	}
+	return `<ac:structured-macro ac:name="` + name + `"><ac:parameter ac:name=""><ac:link><ri:page` + spaceAttr + ` ri:content-title="` + title + `" /></ac:link></ac:parameter></ac:structured-macro>`
+}
+
+func TestIncludeMacros(t *testing.T) {
+	pages := &fakePages{pages: map[string]*confluence.Page{
+		"ENG/Shared": storagePage("2", "ENG", "Shared", `<p>Shared <strong>text</strong></p>`),
+		"OPS/Runbook": storagePage("3", "OPS", "Runbook",
+			`<p>Intro</p><ac:structured-macro ac:name="excerpt"><ac:parameter ac:name="hidden">true</ac:parameter><ac:rich-text-body><p>The summary.</p></ac:rich-text-body></ac:structured-macro>`),
+		"ENG/Outer": storagePage("4", "ENG", "Outer", `<p>Outer</p>`+includeMacro("include", "", "Inner")),
+		"ENG/Inner": storagePage("5", "ENG", "Inner", `<p>Inner</p>`+includeMacro("include", "", "Outer")),
+	}}
+
+	tests := []struct {
+		name  string
+		input string
+		depth int
+		want  string
+	}{
+		{
+			name:  "include from same space",
+			input: `<p>Before</p>` + includeMacro("include", "", "Shared") + `<p>After</p>`,
+			depth: 3,
+			want:  "Before\n\nShared **text**\n\nAfter",
+		},
+		{
+			name:  "excerpt include from other space",
+			input: includeMacro("excerpt-include", "OPS", "Runbook"),
+			depth: 3,
+			want:  "The summary.",
+		},
+		{
+			name:  "missing page",
+			input: includeMacro("include", "", "Gone"),
+			depth: 3,
+			want:  `<!-- could not include "Gone": page "Gone" not found in space ENG -->`,
+		},
+		{
+			name:  "cycle",
+			input: includeMacro("include", "", "Outer"),
+			depth: 5,
+			want:  "Outer\n\nInner\n\n<!-- not included \"Outer\": include cycle -->",
+		},
+		{
+			name:  "depth limit",
+			input: includeMacro("include", "", "Outer"),
+			depth: 1,
+			want:  "Outer\n\n<!-- not included \"Inner\": include depth limit 1 reached -->",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConverter(WithIncludes(pages, tt.depth))
+			page := storagePage("1", "ENG", "T", tt.input)
+			got, err := c.PageToMarkdown(page, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != "# T\n\n"+tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, "# T\n\n"+tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludeMacros_Cached(t *testing.T) {
+	pages := &fakePages{pages: map[string]*confluence.Page{
+		"ENG/Shared": storagePage("2", "ENG", "Shared", `<p>Shared</p>`),
+	}}
+	c := NewConverter(WithIncludes(pages, 3))
+	convertStorage(t, c, includeMacro("include", "ENG", "Shared")+includeMacro("include", "ENG", "Shared"))
+	if pages.fetches != 1 {
+		t.Errorf("expected included page to be fetched once, got %d fetches", pages.fetches)
+	}
+}
+
+func TestIncludeMacros_Disabled(t *testing.T) {
+	got := convertStorage(t, NewConverter(WithUnknownMacroPolicy(UnknownMacroComment)), `<p>Before</p>`+includeMacro("include", "", "Shared"))
+	if got != "Before\n\n<!-- confluence macro: include =\"\" -->" {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package markdown
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+// JiraResolver looks up the issues referenced by jira macros.
+// *confluence.JiraClient implements it.
+type JiraResolver interface {
+	GetIssue(key string) (*confluence.JiraIssue, error)
+	SearchIssues(jql string, fields []string, limit int) ([]confluence.JiraIssue, error)
+}
+
+// WithJira links jira macros to issues on the Jira site at baseURL. When r
+// is non-nil, single issues also show their summary and status, and JQL
+// macros are rendered as tables of matching issues.
+func WithJira(baseURL string, r JiraResolver) Option {
+	return func(c *Converter) {
+		c.jiraURL = strings.TrimSuffix(baseURL, "/")
+		c.jira = r
+	}
+}
+
+// jiraDefaultColumns are the columns Confluence shows for a JQL macro
+// without a columns parameter.
+const jiraDefaultColumns = "type,key,summary,assignee,reporter,priority,status,resolution,created,updated,due"
+
+// jiraFieldIDs maps jira macro column names to Jira field IDs where they
+// differ.
+var jiraFieldIDs = map[string]string{
+	"type": "issuetype",
+	"due":  "duedate",
+}
+
+// renderJiraMacro renders single-issue jira macros as links to the issue,
+// and JQL macros as a table of issues or a link to the search.
+func renderJiraMacro(m *Macro) (string, error) {
+	c := m.converter
+	if key := m.Parameters["key"]; key != "" {
+		return c.renderJiraIssue(key), nil
+	}
+	if jql := m.Parameters["jqlQuery"]; jql != "" {
+		if c.jira != nil && !m.Inline {
+			if table, ok := c.renderJiraTable(m, jql); ok {
+				return table, nil
+			}
+		}
+		return c.jiraSearchLink(jql), nil
+	}
+	return c.renderUnknownMacro(m)
+}
+
+func (c *Converter) renderJiraIssue(key string) string {
+	link := c.jiraIssueLink(key)
+	if c.jira == nil {
+		return link
+	}
+	issue, err := c.jira.GetIssue(key)
+	if err != nil {
+		return link
+	}
+	if summary := issue.Field("summary"); summary != "" {
+		link += " " + markdownEscaper.Replace(summary)
+	}
+	if status := issue.Field("status"); status != "" {
+		link += " [" + strings.ToUpper(status) + "]"
+	}
+	return link
+}
+
+// renderJiraTable renders the issues matching a JQL macro's query with the
+// macro's columns. It reports false if the search fails.
+func (c *Converter) renderJiraTable(m *Macro, jql string) (string, bool) {
+	columnList := m.Parameters["columns"]
+	if columnList == "" {
+		columnList = jiraDefaultColumns
+	}
+	var columns, fields []string
+	for _, col := range strings.Split(columnList, ",") {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if col == "" {
+			continue
+		}
+		columns = append(columns, col)
+		if id, ok := jiraFieldIDs[col]; ok {
+			fields = append(fields, id)
+		} else if col != "key" {
+			fields = append(fields, col)
+		}
+	}
+
+	limit := 20
+	if n, err := strconv.Atoi(m.Parameters["maximumIssues"]); err == nil && n > 0 {
+		limit = n
+	}
+
+	issues, err := c.jira.SearchIssues(jql, fields, limit)
+	if err != nil {
+		return "", false
+	}
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = strings.ToUpper(col[:1]) + col[1:]
+	}
+
+	var rows [][]string
+	for _, issue := range issues {
+		row := make([]string, len(columns))
+		for i, col := range columns {
+			if col == "key" {
+				row[i] = c.jiraIssueLink(issue.Key)
+				continue
+			}
+			id := col
+			if mapped, ok := jiraFieldIDs[col]; ok {
+				id = mapped
+			}
+			row[i] = jiraCell(issue.Field(id))
+		}
+		rows = append(rows, row)
+	}
+	return pipeTable(header, rows), true
+}
+
+func (c *Converter) jiraIssueLink(key string) string {
+	if c.jiraURL == "" {
+		return key
+	}
+	return "[" + key + "](" + c.jiraURL + "/browse/" + url.PathEscape(key) + ")"
+}
+
+func (c *Converter) jiraSearchLink(jql string) string {
+	text := "Jira issues: " + markdownEscaper.Replace(jql)
+	if c.jiraURL == "" {
+		return text
+	}
+	return "[" + text + "](" + c.jiraURL + "/issues/?jql=" + url.QueryEscape(jql) + ")"
+}
+
+// jiraCell formats a field value for a table cell, shortening timestamps
+// to their date.
+func jiraCell(value string) string {
+	if t, err := time.Parse("2006-01-02T15:04:05.000-0700", value); err == nil {
+		value = t.Format("2006-01-02")
+	}
+	value = markdownEscaper.Replace(value)
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
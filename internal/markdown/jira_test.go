@@ -0,0 +1,91 @@
+package markdown
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+type fakeJira struct{}
+
+func (fakeJira) GetIssue(key string) (*confluence.JiraIssue, error) {
+	if key != "ENG-1" {
+		return nil, fmt.Errorf("issue %s not found", key)
+	}
+	return &confluence.JiraIssue{Key: key, Fields: map[string]interface{}{
+		"summary": "Fix *login*",
+		"status":  map[string]interface{}{"name": "In Progress"},
+	}}, nil
+}
+
+func (fakeJira) SearchIssues(jql string, fields []string, limit int) ([]confluence.JiraIssue, error) {
+	return []confluence.JiraIssue{
+		{Key: "ENG-1", Fields: map[string]interface{}{
+			"summary": "Fix login | SSO",
+			"status":  map[string]interface{}{"name": "Done"},
+			"created": "2024-03-05T10:15:00.000+0000",
+		}},
+	}, nil
+}
+
+func TestJiraMacro(t *testing.T) {
+	issue := func(key string) string {
+		return `<p>See <ac:structured-macro ac:name="jira"><ac:parameter ac:name="server">System JIRA</ac:parameter><ac:parameter ac:name="key">` + key + `</ac:parameter></ac:structured-macro></p>`
+	}
+	jql := `<ac:structured-macro ac:name="jira"><ac:parameter ac:name="jqlQuery">project = ENG</ac:parameter><ac:parameter ac:name="columns">key,summary,status,created</ac:parameter></ac:structured-macro>`
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		input string
+		want  string
+	}{
+		{
+			name:  "issue key without Jira URL",
+			input: issue("ENG-1"),
+			want:  "See ENG-1",
+		},
+		{
+			name:  "issue link",
+			opts:  []Option{WithJira("https://example.atlassian.net/", nil)},
+			input: issue("ENG-1"),
+			want:  "See [ENG-1](https://example.atlassian.net/browse/ENG-1)",
+		},
+		{
+			name:  "issue details",
+			opts:  []Option{WithJira("https://example.atlassian.net", fakeJira{})},
+			input: issue("ENG-1"),
+			want:  `See [ENG-1](https://example.atlassian.net/browse/ENG-1) Fix \*login\* [IN PROGRESS]`,
+		},
+		{
+			name:  "issue lookup fails",
+			opts:  []Option{WithJira("https://example.atlassian.net", fakeJira{})},
+			input: issue("ENG-2"),
+			want:  "See [ENG-2](https://example.atlassian.net/browse/ENG-2)",
+		},
+		{
+			name:  "JQL link",
+			opts:  []Option{WithJira("https://example.atlassian.net", nil)},
+			input: jql,
+			want:  "[Jira issues: project = ENG](https://example.atlassian.net/issues/?jql=project+%3D+ENG)",
+		},
+		{
+			name:  "JQL table",
+			opts:  []Option{WithJira("https://example.atlassian.net", fakeJira{})},
+			input: jql,
+			want: "| Key | Summary | Status | Created |\n" +
+				"| --- | --- | --- | --- |\n" +
+				`| [ENG-1](https://example.atlassian.net/browse/ENG-1) | Fix login \| SSO | Done | 2024-03-05 |`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertStorage(t, NewConverter(tt.opts...), tt.input)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// WithLayoutSeparators puts a horizontal rule between the columns of page
+// layouts and section macros once they are flattened into a single column.
+func WithLayoutSeparators(enabled bool) Option {
+	return func(c *Converter) {
+		c.layoutRules = enabled
+	}
+}
+
+// flattenLayouts replaces each ac:layout in n's subtree with the content
+// of its cells in reading order: sections top to bottom, cells left to
+// right. Empty cells are dropped.
+func (c *Converter) flattenLayouts(n *storage.Node, ph *placeholders) {
+	for _, layout := range n.FindAll("ac:layout") {
+		var content []*storage.Node
+		for _, section := range layout.Children {
+			if !section.Is("ac:layout-section") {
+				continue
+			}
+			for _, cell := range section.Children {
+				if !cell.Is("ac:layout-cell") || !hasContent(cell) {
+					continue
+				}
+				if len(content) > 0 && c.layoutRules {
+					rule := storage.NewElement("p")
+					rule.AppendChild(storage.NewText(ph.add("---", false)))
+					content = append(content, rule)
+				}
+				div := storage.NewElement("div")
+				for _, child := range append([]*storage.Node(nil), cell.Children...) {
+					div.AppendChild(child)
+				}
+				content = append(content, div)
+			}
+		}
+		layout.ReplaceWith(content...)
+	}
+}
+
+// hasContent reports whether n contains any text or embedded content.
+func hasContent(n *storage.Node) bool {
+	if strings.TrimSpace(n.Text()) != "" {
+		return true
+	}
+	for _, name := range []string{"img", "table", "hr"} {
+		if n.Find(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// renderSectionMacro renders a section macro's columns one after another.
+// Content outside column macros is kept in place between them.
+func renderSectionMacro(m *Macro) (string, error) {
+	body := m.Node.Child("ac:rich-text-body")
+	if body == nil {
+		return "", nil
+	}
+
+	c := m.converter
+	var parts []string
+	var pending []*storage.Node
+	flush := func() error {
+		md, err := c.nodesToMarkdown(pending, m.ctx)
+		pending = nil
+		if md != "" {
+			parts = append(parts, md)
+		}
+		return err
+	}
+
+	for _, child := range append([]*storage.Node(nil), body.Children...) {
+		if !child.Is("ac:structured-macro") || child.Attr("ac:name") != "column" {
+			pending = append(pending, child)
+			continue
+		}
+		if err := flush(); err != nil {
+			return "", err
+		}
+		md, err := c.newMacro(child, false, m.ctx).Body()
+		if err != nil {
+			return "", err
+		}
+		if md != "" {
+			parts = append(parts, md)
+		}
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+
+	sep := "\n\n"
+	if c.layoutRules {
+		sep = "\n\n---\n\n"
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// renderColumnMacro renders a column macro outside a section as its body.
+func renderColumnMacro(m *Macro) (string, error) {
+	return m.Body()
+}
@@ -0,0 +1,54 @@
+package markdown
+
+import "testing"
+
+func TestLayouts(t *testing.T) {
+	layout := `<ac:layout><ac:layout-section ac:type="two_equal"><ac:layout-cell><h2>Left</h2><p>one</p></ac:layout-cell>` +
+		"\n" + `<ac:layout-cell>two</ac:layout-cell></ac:layout-section>` +
+		`<ac:layout-section ac:type="single"><ac:layout-cell><p></p></ac:layout-cell></ac:layout-section>` +
+		`<ac:layout-section ac:type="single"><ac:layout-cell><p>three</p></ac:layout-cell></ac:layout-section></ac:layout>`
+	section := `<ac:structured-macro ac:name="section"><ac:rich-text-body>` +
+		`<ac:structured-macro ac:name="column"><ac:parameter ac:name="width">30%</ac:parameter><ac:rich-text-body><p>Menu</p></ac:rich-text-body></ac:structured-macro>` +
+		`<ac:structured-macro ac:name="column"><ac:rich-text-body><ac:structured-macro ac:name="info"><ac:rich-text-body><p>Main</p></ac:rich-text-body></ac:structured-macro></ac:rich-text-body></ac:structured-macro>` +
+		`</ac:rich-text-body></ac:structured-macro>`
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		input string
+		want  string
+	}{
+		{
+			name:  "layout in reading order",
+			input: layout,
+			want:  "## Left\n\none\n\ntwo\n\nthree",
+		},
+		{
+			name:  "layout with separators",
+			opts:  []Option{WithLayoutSeparators(true)},
+			input: layout,
+			want:  "## Left\n\none\n\n---\n\ntwo\n\n---\n\nthree",
+		},
+		{
+			name:  "section and column macros",
+			opts:  []Option{WithUnknownMacroPolicy(UnknownMacroDrop)},
+			input: section,
+			want:  "Menu\n\n> [!NOTE]\n> Main",
+		},
+		{
+			name:  "section with separators",
+			opts:  []Option{WithLayoutSeparators(true)},
+			input: section,
+			want:  "Menu\n\n---\n\n> [!NOTE]\n> Main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertStorage(t, NewConverter(tt.opts...), tt.input)
+			if got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package markdown
+
+import (
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// PageLinker maps links to other Confluence pages onto the files those
+// pages are exported to.
+type PageLinker interface {
+	// PageLink returns the link destination, as seen from the page from,
+	// for the page with the given space key and title, and the anchor on
+	// it if not empty. ok is false for pages that weren't exported.
+	PageLink(from *confluence.Page, spaceKey, title, anchor string) (link string, ok bool)
+}
+
+// WithPageLinks turns links to other pages into links to their exported
+// files through l. Without it, such links become their text.
+func WithPageLinks(l PageLinker) Option {
+	return func(c *Converter) {
+		c.pageLinks = l
+	}
+}
+
+// resolvePageLinks replaces links to pages the page linker knows with <a>
+// elements pointing at their files.
+func (c *Converter) resolvePageLinks(n *storage.Node, ctx renderContext) {
+	if c.pageLinks == nil || ctx.from == nil {
+		return
+	}
+	for _, link := range n.FindAll("ac:link") {
+		ref := link.Child("ri:page")
+		if ref == nil {
+			continue
+		}
+		title := ref.Attr("ri:content-title")
+		spaceKey := ref.Attr("ri:space-key")
+		if spaceKey == "" && ctx.page != nil {
+			spaceKey = ctx.page.Space.Key
+		}
+		href, ok := c.pageLinks.PageLink(ctx.from, spaceKey, title, link.Attr("ac:anchor"))
+		if !ok {
+			continue
+		}
+
+		body := []*storage.Node{storage.NewText(title)}
+		if b := link.Child("ac:link-body"); b != nil {
+			body = b.Children
+		} else if b := link.Child("ac:plain-text-link-body"); b != nil {
+			body = []*storage.Node{storage.NewText(b.Text())}
+		}
+		a := storage.NewElement("a", storage.Attr{Name: "href", Value: href})
+		for _, child := range append([]*storage.Node(nil), body...) {
+			a.AppendChild(child)
+		}
+		link.ReplaceWith(a)
+	}
+}
+
+// Slug turns a page title into a lower-case, hyphenated name for URLs and
+// file names, like GitHub's heading anchors.
+func Slug(title string) string {
+	if slug := newSlugger().slug(title); slug != "" {
+		return slug
+	}
+	return "page"
+}
@@ -0,0 +1,91 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+// fakeLinker links to pages it knows by "SPACE/Title", recording the page
+// each link was written from.
+type fakeLinker map[string]string
+
+func (f fakeLinker) PageLink(from *confluence.Page, spaceKey, title, anchor string) (string, bool) {
+	file, ok := f[spaceKey+"/"+title]
+	if !ok {
+		return "", false
+	}
+	link := from.ID + ":" + file
+	if anchor != "" {
+		link += "#" + anchor
+	}
+	return link, true
+}
+
+func TestPageLinks(t *testing.T) {
+	linker := fakeLinker{"ENG/Runbook": "runbook.md", "OPS/Alerts": "ops/alerts.md"}
+	pages := &fakePages{pages: map[string]*confluence.Page{
+		"ENG/Shared": storagePage("2", "ENG", "Shared", `<p><ac:link><ri:page ri:content-title="Runbook" /></ac:link></p>`),
+	}}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "title as text",
+			input: `<p>See <ac:link><ri:page ri:content-title="Runbook" /></ac:link>.</p>`,
+			want:  "See [Runbook](1:runbook.md).",
+		},
+		{
+			name:  "link body and anchor",
+			input: `<p><ac:link ac:anchor="rollback"><ri:page ri:content-title="Runbook" /><ac:link-body><strong>roll back</strong></ac:link-body></ac:link></p>`,
+			want:  "[**roll back**](1:runbook.md#rollback)",
+		},
+		{
+			name:  "other space",
+			input: `<p><ac:link><ri:page ri:space-key="OPS" ri:content-title="Alerts" /><ac:plain-text-link-body><![CDATA[alerts]]></ac:plain-text-link-body></ac:link></p>`,
+			want:  "[alerts](1:ops/alerts.md)",
+		},
+		{
+			name:  "page not exported",
+			input: `<p><ac:link><ri:page ri:content-title="Elsewhere" /></ac:link></p>`,
+			want:  "Elsewhere",
+		},
+		{
+			name:  "link in included page is written from the including page",
+			input: includeMacro("include", "", "Shared"),
+			want:  "[Runbook](1:runbook.md)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConverter(WithPageLinks(linker), WithIncludes(pages, 3))
+			got, err := c.PageToMarkdown(storagePage("1", "ENG", "T", tt.input), false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != "# T\n\n"+tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, "# T\n\n"+tt.want)
+			}
+		})
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Getting Started", "getting-started"},
+		{"CI/CD: Setup?", "cicd-setup"},
+		{"???", "page"},
+	}
+	for _, tt := range tests {
+		if got := Slug(tt.title); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package markdown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// MacroHandler renders a Confluence structured macro
+// (<ac:structured-macro ac:name="...">) as Markdown.
+type MacroHandler interface {
+	RenderMacro(m *Macro) (string, error)
+}
+
+// MacroHandlerFunc adapts an ordinary function to a MacroHandler.
+type MacroHandlerFunc func(m *Macro) (string, error)
+
+func (f MacroHandlerFunc) RenderMacro(m *Macro) (string, error) {
+	return f(m)
+}
+
+// UnknownMacroPolicy controls how macros without a registered handler are
+// rendered.
+type UnknownMacroPolicy string
+
+const (
+	// UnknownMacroKeep renders the macro's body, if any, in its place.
+	UnknownMacroKeep UnknownMacroPolicy = "keep"
+	// UnknownMacroDrop removes the macro and its body.
+	UnknownMacroDrop UnknownMacroPolicy = "drop"
+	// UnknownMacroComment replaces the macro with an HTML comment naming it.
+	UnknownMacroComment UnknownMacroPolicy = "comment"
+)
+
+// ParseUnknownMacroPolicy validates an --unknown-macros flag value.
+func ParseUnknownMacroPolicy(s string) (UnknownMacroPolicy, error) {
+	switch p := UnknownMacroPolicy(s); p {
+	case UnknownMacroKeep, UnknownMacroDrop, UnknownMacroComment:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown macro policy %q (expected keep, drop or comment)", s)
+}
+
+// Macro is a structured macro being rendered by a MacroHandler.
+type Macro struct {
+	// Name is the macro's ac:name, e.g. "code" or "info".
+	Name string
+	// Parameters holds the macro's ac:parameter values by name.
+	Parameters map[string]string
+	// Inline is true when the macro sits inside a paragraph or other
+	// inline context, where the rendered Markdown should be a single line.
+	Inline bool
+	// Node is the ac:structured-macro element itself.
+	Node *storage.Node
+	// Page is the page the macro appears on, or nil when unknown.
+	Page *confluence.Page
+
+	converter *Converter
+	ctx       renderContext
+}
+
+// PlainTextBody returns the macro's ac:plain-text-body, as used by code
+// and similar macros.
+func (m *Macro) PlainTextBody() string {
+	if body := m.Node.Child("ac:plain-text-body"); body != nil {
+		return body.Text()
+	}
+	return ""
+}
+
+// HasBody reports whether the macro has a rich-text or plain-text body.
+func (m *Macro) HasBody() bool {
+	return m.Node.Child("ac:rich-text-body") != nil || m.Node.Child("ac:plain-text-body") != nil
+}
+
+// Body converts the macro's ac:rich-text-body to Markdown, rendering any
+// nested macros with their handlers.
+func (m *Macro) Body() (string, error) {
+	body := m.Node.Child("ac:rich-text-body")
+	if body == nil {
+		return "", nil
+	}
+	return m.converter.nodesToMarkdown(body.Children, m.ctx)
+}
+
+// nodesToMarkdown converts a sequence of storage nodes, such as a macro
+// body, as a document of its own. The result is trimmed.
+func (c *Converter) nodesToMarkdown(nodes []*storage.Node, ctx renderContext) (string, error) {
+	doc := &storage.Node{Type: storage.DocumentNode}
+	for _, n := range append([]*storage.Node(nil), nodes...) {
+		doc.AppendChild(n)
+	}
+	md, err := c.storageToMarkdown(doc, ctx)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(md), nil
+}
+
+// WithMacroHandler registers a handler for the named macro, replacing any
+// built-in handler for it.
+func WithMacroHandler(name string, h MacroHandler) Option {
+	return func(c *Converter) {
+		c.RegisterMacro(name, h)
+	}
+}
+
+// WithUnknownMacroPolicy sets how macros without a handler are rendered.
+func WithUnknownMacroPolicy(p UnknownMacroPolicy) Option {
+	return func(c *Converter) {
+		c.unknownMacros = p
+	}
+}
+
+// RegisterMacro registers a handler for the named macro, replacing any
+// existing handler for it.
+func (c *Converter) RegisterMacro(name string, h MacroHandler) {
+	c.macros[name] = h
+}
+
+// inlineParents are the elements inside which a macro renders inline.
+var inlineParents = map[string]bool{
+	"p": true, "span": true, "a": true, "strong": true, "em": true,
+	"b": true, "i": true, "u": true, "s": true, "del": true, "code": true,
+	"sub": true, "sup": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true,
+}
+
+// renderMacros replaces every structured macro in n's subtree with a
+// placeholder for its rendered Markdown.
+func (c *Converter) renderMacros(n *storage.Node, ph *placeholders, ctx renderContext) error {
+	for _, child := range append([]*storage.Node(nil), n.Children...) {
+		if !child.Is("ac:structured-macro") {
+			if err := c.renderMacros(child, ph, ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		m := c.newMacro(child, inlineParents[n.Name], ctx)
+		md, err := c.renderMacro(m)
+		if err != nil {
+			return fmt.Errorf("rendering %s macro: %w", m.Name, err)
+		}
+
+		token := storage.NewText(ph.add(md, m.Inline))
+		if m.Inline {
+			child.ReplaceWith(token)
+		} else {
+			p := storage.NewElement("p")
+			p.AppendChild(token)
+			child.ReplaceWith(p)
+		}
+	}
+	return nil
+}
+
+// newMacro describes the structured macro element n for its handler.
+func (c *Converter) newMacro(n *storage.Node, inline bool, ctx renderContext) *Macro {
+	m := &Macro{
+		Name:       n.Attr("ac:name"),
+		Parameters: map[string]string{},
+		Inline:     inline,
+		Node:       n,
+		Page:       ctx.page,
+		converter:  c,
+		ctx:        ctx,
+	}
+	for _, p := range n.Children {
+		if p.Is("ac:parameter") {
+			m.Parameters[p.Attr("ac:name")] = strings.TrimSpace(p.Text())
+		}
+	}
+	return m
+}
+
+func (c *Converter) renderMacro(m *Macro) (string, error) {
+	if h, ok := c.macros[m.Name]; ok {
+		return h.RenderMacro(m)
+	}
+	return c.renderUnknownMacro(m)
+}
+
+// renderUnknownMacro renders a macro according to the unknown-macro policy.
+func (c *Converter) renderUnknownMacro(m *Macro) (string, error) {
+	switch c.unknownMacros {
+	case UnknownMacroDrop:
+		return "", nil
+	case UnknownMacroComment:
+		return macroComment(m), nil
+	default:
+		if m.Node.Child("ac:rich-text-body") != nil {
+			return m.Body()
+		}
+		return strings.TrimSpace(m.PlainTextBody()), nil
+	}
+}
+
+// macroComment renders an HTML comment recording a macro's name and
+// parameters.
+func macroComment(m *Macro) string {
+	var params []string
+	for k, v := range m.Parameters {
+		params = append(params, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(params)
+
+	comment := "confluence macro: " + m.Name
+	if len(params) > 0 {
+		comment += " " + strings.Join(params, " ")
+	}
+	return "<!-- " + strings.ReplaceAll(comment, "--", "- -") + " -->"
+}
+
+// placeholders holds macro output while the surrounding HTML goes through
+// the HTML-to-Markdown converter, which would otherwise escape it.
+type placeholders struct {
+	values []string
+	inline []bool
+}
+
+func (ph *placeholders) add(md string, inline bool) string {
+	ph.values = append(ph.values, md)
+	ph.inline = append(ph.inline, inline)
+	return ph.token(len(ph.values) - 1)
+}
+
+func (ph *placeholders) token(i int) string {
+	return fmt.Sprintf("confluencemdmacro%dend", i)
+}
+
+// hasBlock reports whether md contains a placeholder for block output.
+func (ph *placeholders) hasBlock(md string) bool {
+	for i := range ph.values {
+		if !ph.inline[i] && strings.Contains(md, ph.token(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// expand substitutes rendered macros back into converted Markdown. Block
+// output spanning several lines is indented to match the line the
+// placeholder ended up on, e.g. inside a list item or blockquote.
+func (ph *placeholders) expand(md string) string {
+	if len(ph.values) == 0 {
+		return md
+	}
+
+	lines := strings.Split(md, "\n")
+	removed := map[int]bool{}
+	for i := len(ph.values) - 1; i >= 0; i-- {
+		token := ph.token(i)
+		for j, line := range lines {
+			idx := strings.Index(line, token)
+			if idx < 0 {
+				continue
+			}
+			value := ph.values[i]
+			rest := line[:idx] + line[idx+len(token):]
+
+			// A block macro that rendered nothing takes its line, and the
+			// blank line separating it from the next block, with it.
+			if value == "" && !ph.inline[i] && strings.TrimSpace(rest) == "" {
+				removed[j] = true
+				if j+1 < len(lines) && strings.TrimSpace(lines[j+1]) == "" {
+					removed[j+1] = true
+				}
+				continue
+			}
+
+			if !ph.inline[i] {
+				prefix := continuationPrefix(line[:idx])
+				sub := strings.Split(value, "\n")
+				for k := 1; k < len(sub); k++ {
+					if sub[k] == "" {
+						sub[k] = strings.TrimRight(prefix, " ")
+					} else {
+						sub[k] = prefix + sub[k]
+					}
+				}
+				value = strings.Join(sub, "\n")
+			}
+			lines[j] = line[:idx] + value + line[idx+len(token):]
+		}
+	}
+
+	var out []string
+	for j, line := range lines {
+		if !removed[j] {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// continuationPrefix turns the text before a placeholder into the prefix
+// for following lines: blockquote markers are kept, anything else (list
+// markers, indentation) becomes spaces.
+func continuationPrefix(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		if r == '>' {
+			b.WriteRune('>')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+func convertStorage(t *testing.T, c *Converter, input string) string {
+	t.Helper()
+	page := &confluence.Page{Title: "T", Body: confluence.Body{Storage: confluence.Storage{Value: input}}}
+	result, err := c.PageToMarkdown(page, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return strings.TrimPrefix(result, "# T\n\n")
+}
+
+func TestMacroHandler_Custom(t *testing.T) {
+	c := NewConverter(WithMacroHandler("roadmap", MacroHandlerFunc(func(m *Macro) (string, error) {
+		return "**Roadmap:** " + m.Parameters["quarter"], nil
+	})))
+
+	got := convertStorage(t, c, `<p>Before</p><ac:structured-macro ac:name="roadmap"><ac:parameter ac:name="quarter">Q3</ac:parameter></ac:structured-macro><p>After</p>`)
+	want := "Before\n\n**Roadmap:** Q3\n\nAfter"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMacroHandler_Inline(t *testing.T) {
+	c := NewConverter(WithMacroHandler("jira", MacroHandlerFunc(func(m *Macro) (string, error) {
+		if !m.Inline {
+			t.Error("expected macro inside paragraph to be inline")
+		}
+		return "[" + m.Parameters["key"] + "]", nil
+	})))
+
+	got := convertStorage(t, c, `<p>Fixed in <ac:structured-macro ac:name="jira"><ac:parameter ac:name="key">ENG-1</ac:parameter></ac:structured-macro> today</p>`)
+	if got != "Fixed in [ENG-1] today" {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestUnknownMacroPolicy(t *testing.T) {
+	input := `<p>Before</p><ac:structured-macro ac:name="mystery"><ac:parameter ac:name="mode">fast</ac:parameter><ac:rich-text-body><p>Inner <strong>content</strong></p></ac:rich-text-body></ac:structured-macro><p>After</p>`
+
+	tests := []struct {
+		policy UnknownMacroPolicy
+		want   string
+	}{
+		{UnknownMacroKeep, "Before\n\nInner **content**\n\nAfter"},
+		{UnknownMacroDrop, "Before\n\nAfter"},
+		{UnknownMacroComment, "Before\n\n<!-- confluence macro: mystery mode=\"fast\" -->\n\nAfter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			got := convertStorage(t, NewConverter(WithUnknownMacroPolicy(tt.policy)), input)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinMacros(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "info panel with title",
+			input: `<ac:structured-macro ac:name="info"><ac:parameter ac:name="title">Heads up</ac:parameter><ac:rich-text-body><p>Read this.</p></ac:rich-text-body></ac:structured-macro>`,
+			want:  "> [!NOTE]\n> **Heads up**\n>\n> Read this.",
+		},
+		{
+			name:  "nested code macro in warning",
+			input: `<ac:structured-macro ac:name="warning"><ac:rich-text-body><p>Run:</p><ac:structured-macro ac:name="code"><ac:plain-text-body><![CDATA[rm -rf build]]></ac:plain-text-body></ac:structured-macro></ac:rich-text-body></ac:structured-macro>`,
+			want:  "> [!WARNING]\n> Run:\n>\n> ```\n> rm -rf build\n> ```",
+		},
+		{
+			name: "code macro in list item",
+			input: `<ul><li><p>Step one</p><ac:structured-macro ac:name="code"><ac:parameter ac:name="language">sh</ac:parameter><ac:plain-text-body><![CDATA[make
+make install]]></ac:plain-text-body></ac:structured-macro></li></ul>`,
+			want: "- Step one\n\n  ```sh\n  make\n  make install\n  ```",
+		},
+		{
+			name:  "expand",
+			input: `<ac:structured-macro ac:name="expand"><ac:parameter ac:name="title">Show logs</ac:parameter><ac:rich-text-body><p>Line one</p></ac:rich-text-body></ac:structured-macro>`,
+			want:  "<details>\n<summary>Show logs</summary>\n\nLine one\n\n</details>",
+		},
+		{
+			name:  "expand title with markup",
+			input: `<ac:structured-macro ac:name="expand"><ac:parameter ac:name="title">a &lt;b&gt; c</ac:parameter><ac:rich-text-body><p>x</p></ac:rich-text-body></ac:structured-macro>`,
+			want:  "<details>\n<summary>a &lt;b&gt; c</summary>\n\nx\n\n</details>",
+		},
+		{
+			name:  "status lozenge",
+			input: `<p>State: <ac:structured-macro ac:name="status"><ac:parameter ac:name="colour">Blue</ac:parameter><ac:parameter ac:name="title">In progress</ac:parameter></ac:structured-macro></p>`,
+			want:  "State: [IN PROGRESS]",
+		},
+		{
+			name:  "anchor",
+			input: `<p><ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">setup</ac:parameter></ac:structured-macro>Setup notes</p>`,
+			want:  `<a id="setup"></a>Setup notes`,
+		},
+		{
+			name:  "anchor with quote",
+			input: `<p><ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">x"y</ac:parameter></ac:structured-macro>Notes</p>`,
+			want:  `<a id="x&#34;y"></a>Notes`,
+		},
+		{
+			name:  "table of contents",
+			input: `<p><ac:structured-macro ac:name="toc"><ac:parameter ac:name="maxLevel">3</ac:parameter></ac:structured-macro></p><h2>Setup</h2><h3>Install &amp; run</h3><h4>Too deep</h4><h2>Setup</h2>`,
+			want:  "- [Setup](#setup)\n  - [Install & run](#install--run)\n- [Setup](#setup-1)",
+		},
+		{
+			name:  "table of contents in info panel",
+			input: `<ac:structured-macro ac:name="info"><ac:rich-text-body><ac:structured-macro ac:name="toc" /></ac:rich-text-body></ac:structured-macro><h2>Setup</h2><h2>Rollback</h2>`,
+			want:  "> [!NOTE]\n> - [Setup](#setup)\n> - [Rollback](#rollback)",
+		},
+		{
+			name:  "table of contents in column",
+			input: `<ac:structured-macro ac:name="section"><ac:rich-text-body><ac:structured-macro ac:name="column"><ac:rich-text-body><ac:structured-macro ac:name="toc" /></ac:rich-text-body></ac:structured-macro><ac:structured-macro ac:name="column"><ac:rich-text-body><h2>Setup</h2></ac:rich-text-body></ac:structured-macro></ac:rich-text-body></ac:structured-macro><h2>Rollback</h2>`,
+			want:  "- [Setup](#setup)\n- [Rollback](#rollback)",
+		},
+		{
+			name:  "table of contents after expand with headings",
+			input: `<ac:structured-macro ac:name="expand"><ac:rich-text-body><h2>Hidden</h2></ac:rich-text-body></ac:structured-macro><ac:structured-macro ac:name="note"><ac:rich-text-body><ac:structured-macro ac:name="toc" /></ac:rich-text-body></ac:structured-macro>`,
+			want:  "> - [Hidden](#hidden)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertStorage(t, NewConverter(), tt.input)
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseUnknownMacroPolicy(t *testing.T) {
+	if _, err := ParseUnknownMacroPolicy("explode"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+	if p, err := ParseUnknownMacroPolicy("drop"); err != nil || p != UnknownMacroDrop {
+		t.Errorf("expected drop policy, got %q, %v", p, err)
+	}
+}
+
+func TestStatusMacro_Emoji(t *testing.T) {
+	got := convertStorage(t, NewConverter(WithStatusEmoji(true)), `<p><ac:structured-macro ac:name="status"><ac:parameter ac:name="colour">Green</ac:parameter><ac:parameter ac:name="title">Done</ac:parameter></ac:structured-macro></p>`)
+	if got != "🟢 [DONE]" {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
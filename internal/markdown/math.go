@@ -0,0 +1,37 @@
+package markdown
+
+import "strings"
+
+// inlineMathMacros are the math macros that typeset a formula within a
+// line of text. Every other math macro is a display (block) formula.
+var inlineMathMacros = map[string]bool{
+	"mathinline":           true,
+	"mathjax-inline-macro": true,
+	"latex-inline":         true,
+}
+
+// renderMathMacro renders LaTeX/MathJax macros as $...$ (inline) or
+// $$...$$ (display) Markdown math. The TeX source is kept exactly as
+// written, apart from surrounding whitespace.
+func renderMathMacro(m *Macro) (string, error) {
+	tex := m.PlainTextBody()
+	for _, name := range []string{"body", "equation", "formula"} {
+		if tex != "" {
+			break
+		}
+		tex = m.Parameters[name]
+	}
+	tex = strings.TrimSpace(tex)
+	if tex == "" {
+		return "", nil
+	}
+
+	switch {
+	case inlineMathMacros[m.Name]:
+		return "$" + tex + "$", nil
+	case m.Inline:
+		return "$$" + tex + "$$", nil
+	default:
+		return "$$\n" + tex + "\n$$", nil
+	}
+}
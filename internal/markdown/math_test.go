@@ -0,0 +1,43 @@
+package markdown
+
+import "testing"
+
+func TestMathMacros(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "inline formula",
+			input: `<p>Energy is <ac:structured-macro ac:name="mathinline"><ac:parameter ac:name="body">E = mc^2</ac:parameter></ac:structured-macro> here.</p>`,
+			want:  "Energy is $E = mc^2$ here.",
+		},
+		{
+			name:  "block formula keeps TeX untouched",
+			input: `<ac:structured-macro ac:name="mathblock"><ac:plain-text-body><![CDATA[\sum_{i=1}^{n} x_i * \frac{a_b}{c_d} \\ [1]]]></ac:plain-text-body></ac:structured-macro>`,
+			want:  "$$\n\\sum_{i=1}^{n} x_i * \\frac{a_b}{c_d} \\\\ [1]\n$$",
+		},
+		{
+			name:  "block macro inside paragraph",
+			input: `<p>See <ac:structured-macro ac:name="latex"><ac:plain-text-body><![CDATA[\alpha_1]]></ac:plain-text-body></ac:structured-macro></p>`,
+			want:  "See $$\\alpha_1$$",
+		},
+		{
+			name: "mathjax block",
+			input: `<ac:structured-macro ac:name="mathjax-block-macro"><ac:plain-text-body><![CDATA[
+x^2 + y^2 = z^2
+]]></ac:plain-text-body></ac:structured-macro>`,
+			want: "$$\nx^2 + y^2 = z^2\n$$",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertStorage(t, NewConverter(), tt.input)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// UserResolver looks up the users mentioned on a page.
+// *confluence.Client implements it.
+type UserResolver interface {
+	GetUser(ref confluence.UserRef) (*confluence.User, error)
+}
+
+// WithUserResolver resolves user mentions to display names through r.
+// With links enabled, mentions also link to the user's profile.
+func WithUserResolver(r UserResolver, links bool) Option {
+	return func(c *Converter) {
+		c.users = r
+		c.userLinks = links
+	}
+}
+
+// convertMentions replaces ac:link user mentions in n's subtree with
+// "@Display Name" text. Users that can't be looked up are shown by their
+// account ID or username instead.
+func (c *Converter) convertMentions(n *storage.Node) {
+	for _, link := range n.FindAll("ac:link") {
+		u := link.Child("ri:user")
+		if u == nil {
+			continue
+		}
+		ref := confluence.UserRef{
+			AccountID: u.Attr("ri:account-id"),
+			Username:  u.Attr("ri:username"),
+			UserKey:   u.Attr("ri:userkey"),
+		}
+
+		name, profile := ref.String(), ""
+		if c.users != nil {
+			if user, err := c.users.GetUser(ref); err == nil {
+				name, profile = user.Name(), user.ProfileURL
+			}
+		}
+		if b := link.Child("ac:plain-text-link-body"); b != nil && strings.TrimSpace(b.Text()) != "" {
+			name = strings.TrimPrefix(strings.TrimSpace(b.Text()), "@")
+		}
+
+		mention := storage.NewText("@" + name)
+		if !c.userLinks || profile == "" {
+			link.ReplaceWith(mention)
+			continue
+		}
+		a := storage.NewElement("a", storage.Attr{Name: "href", Value: profile})
+		a.AppendChild(mention)
+		link.ReplaceWith(a)
+	}
+}
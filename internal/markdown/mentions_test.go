@@ -0,0 +1,70 @@
+package markdown
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/justinabrahms/confluence-md/internal/confluence"
+)
+
+type fakeUsers map[string]*confluence.User
+
+func (f fakeUsers) GetUser(ref confluence.UserRef) (*confluence.User, error) {
+	if u, ok := f[ref.String()]; ok {
+		return u, nil
+	}
+	return nil, fmt.Errorf("user %s not found", ref)
+}
+
+func TestUserMentions(t *testing.T) {
+	users := fakeUsers{
+		"5b10ac": {AccountID: "5b10ac", DisplayName: "Jane Doe", ProfileURL: "https://example.atlassian.net/wiki/people/5b10ac"},
+		"jsmith": {Username: "jsmith", DisplayName: "John Smith"},
+	}
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		input string
+		want  string
+	}{
+		{
+			name:  "account id",
+			opts:  []Option{WithUserResolver(users, false)},
+			input: `<p>Assigned to <ac:link><ri:user ri:account-id="5b10ac" /></ac:link> today</p>`,
+			want:  "Assigned to @Jane Doe today",
+		},
+		{
+			name:  "data center username",
+			opts:  []Option{WithUserResolver(users, false)},
+			input: `<p>Ask <ac:link><ri:user ri:username="jsmith" /></ac:link></p>`,
+			want:  "Ask @John Smith",
+		},
+		{
+			name:  "profile link",
+			opts:  []Option{WithUserResolver(users, true)},
+			input: `<p>Ask <ac:link><ri:user ri:account-id="5b10ac" /></ac:link></p>`,
+			want:  "Ask [@Jane Doe](https://example.atlassian.net/wiki/people/5b10ac)",
+		},
+		{
+			name:  "unknown user",
+			opts:  []Option{WithUserResolver(users, true)},
+			input: `<p>Ask <ac:link><ri:user ri:account-id="gone" /></ac:link></p>`,
+			want:  "Ask @gone",
+		},
+		{
+			name:  "no resolver",
+			input: `<p>Ask <ac:link><ri:user ri:userkey="ff80" /></ac:link></p>`,
+			want:  "Ask @ff80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertStorage(t, NewConverter(tt.opts...), tt.input)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
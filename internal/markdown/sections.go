@@ -0,0 +1,148 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Section is a heading in a Markdown document and the lines it covers: from
+// the heading down to the next heading of the same or a higher level.
+type Section struct {
+	Level int
+	Title string
+	// Path holds the titles of the enclosing headings, outermost first,
+	// ending with Title.
+	Path []string
+	// Start and End are line indexes; End is exclusive.
+	Start, End int
+}
+
+var (
+	atxHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceOpening = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	inlineLink   = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// Sections returns the ATX headings in md in document order. Lines inside
+// fenced code blocks are not considered headings.
+func Sections(md string) []Section {
+	lines := strings.Split(md, "\n")
+	var sections []Section
+	var stack []int // indexes into sections of the enclosing headings
+	fence := ""
+	for i, line := range lines {
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+			continue
+		}
+		if m := fenceOpening.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			continue
+		}
+		m := atxHeading.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		level := len(m[1])
+		for len(stack) > 0 && sections[stack[len(stack)-1]].Level >= level {
+			sections[stack[len(stack)-1]].End = i
+			stack = stack[:len(stack)-1]
+		}
+		title := headingText(m[2])
+		var path []string
+		for _, s := range stack {
+			path = append(path, sections[s].Title)
+		}
+		sections = append(sections, Section{Level: level, Title: title, Path: append(path, title), Start: i})
+		stack = append(stack, len(sections)-1)
+	}
+	for _, s := range stack {
+		sections[s].End = len(lines)
+	}
+	return sections
+}
+
+// headingText strips inline formatting from a heading so it can be matched
+// against the title shown in Confluence.
+func headingText(s string) string {
+	s = inlineLink.ReplaceAllString(s, "$1")
+	s = strings.NewReplacer("**", "", "__", "", "`", "").Replace(s)
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ExtractSection returns the Markdown under the heading named by path,
+// including the heading itself. Path is a heading title, optionally
+// preceded by the titles of enclosing headings separated by "/", as in
+// "Setup/Install"; enclosing headings may be skipped. Titles are matched
+// case-insensitively. A title that itself contains "/" is matched whole
+// before the path is split.
+func ExtractSection(md, path string) (string, error) {
+	sections := Sections(md)
+	matches := matchSections(sections, []string{path})
+	if len(matches) == 0 && strings.Contains(path, "/") {
+		matches = matchSections(sections, strings.Split(path, "/"))
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("section %q not found", path)
+	case 1:
+	default:
+		var paths []string
+		for _, s := range matches {
+			paths = append(paths, strings.Join(s.Path, "/"))
+		}
+		return "", fmt.Errorf("section %q is ambiguous, use a heading path: %s", path, strings.Join(paths, ", "))
+	}
+
+	lines := strings.Split(md, "\n")
+	s := matches[0]
+	return strings.TrimRight(strings.Join(lines[s.Start:s.End], "\n"), "\n") + "\n", nil
+}
+
+// matchSections returns the sections whose title matches the last segment
+// and whose enclosing headings contain the other segments in order.
+func matchSections(sections []Section, segments []string) []Section {
+	for i := range segments {
+		segments[i] = strings.TrimSpace(segments[i])
+	}
+	var matches []Section
+	for _, s := range sections {
+		if !strings.EqualFold(s.Title, segments[len(segments)-1]) {
+			continue
+		}
+		want := segments[:len(segments)-1]
+		for _, title := range s.Path[:len(s.Path)-1] {
+			if len(want) > 0 && strings.EqualFold(title, want[0]) {
+				want = want[1:]
+			}
+		}
+		if len(want) == 0 {
+			matches = append(matches, s)
+		}
+	}
+	return matches
+}
+
+// SectionOutline lists the headings in md, indented by level.
+func SectionOutline(md string) string {
+	var b strings.Builder
+	for _, s := range Sections(md) {
+		b.WriteString(strings.Repeat("  ", s.Level-1) + s.Title + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const runbook = `# Runbook
+
+Intro
+
+## Setup
+
+Before you start.
+
+### Install
+
+Run the installer.
+
+` + "```sh\n# not a heading\nmake install\n```" + `
+
+### Configure
+
+Edit the config.
+
+## Deploy
+
+### Install
+
+Deploy the installer.
+
+## CI/CD
+
+Pipelines.
+`
+
+func TestExtractSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "heading down to next sibling",
+			path: "Configure",
+			want: "### Configure\n\nEdit the config.\n",
+		},
+		{
+			name: "includes subsections and code blocks",
+			path: "setup",
+			want: "## Setup\n\nBefore you start.\n\n### Install\n\nRun the installer.\n\n```sh\n# not a heading\nmake install\n```\n\n### Configure\n\nEdit the config.\n",
+		},
+		{
+			name: "heading path",
+			path: "Deploy/Install",
+			want: "### Install\n\nDeploy the installer.\n",
+		},
+		{
+			name: "heading path skipping levels",
+			path: "Runbook/Configure",
+			want: "### Configure\n\nEdit the config.\n",
+		},
+		{
+			name: "title containing a slash",
+			path: "CI/CD",
+			want: "## CI/CD\n\nPipelines.\n",
+		},
+		{
+			name:    "ambiguous title",
+			path:    "Install",
+			wantErr: "Runbook/Setup/Install, Runbook/Deploy/Install",
+		},
+		{
+			name:    "missing section",
+			path:    "Rollback",
+			wantErr: "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractSection(runbook, tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSectionOutline(t *testing.T) {
+	want := "Runbook\n  Setup\n    Install\n    Configure\n  Deploy\n    Install\n  CI/CD\n"
+	if got := SectionOutline(runbook); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestHeadingText(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"**Setup** guide", "Setup guide"},
+		{"[Install](https://example.com) steps", "Install steps"},
+		{"Use `make`", "Use make"},
+		{`snake\_case`, "snake_case"},
+	}
+	for _, tt := range tests {
+		if got := headingText(tt.input); got != tt.want {
+			t.Errorf("headingText(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// alertPattern matches the first line of a GFM alert blockquote, e.g. "> [!NOTE]".
+var alertPattern = regexp.MustCompile(`^\s*\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*$`)
+
+// alertMacros maps GFM alert types to the Confluence panel macro that
+// renders them closest.
+var alertMacros = map[string]string{
+	"NOTE":      "info",
+	"TIP":       "tip",
+	"IMPORTANT": "note",
+	"WARNING":   "warning",
+	"CAUTION":   "warning",
+}
+
+// ToStorage converts Markdown to Confluence storage format.
+//
+// A level-one heading at the very start of the document is treated as the
+// page title, as produced by PageToMarkdown: it is removed from the body
+// and returned separately. title is empty when there is no such heading.
+func ToStorage(src []byte) (title string, storage string, err error) {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	if h, ok := doc.FirstChild().(*ast.Heading); ok && h.Level == 1 {
+		title = strings.TrimSpace(string(plainText(h, src)))
+		doc.RemoveChild(doc, h)
+	}
+
+	var buf bytes.Buffer
+	r := &storageRenderer{src: src, w: bufio.NewWriter(&buf), alerts: map[ast.Node]bool{}}
+	if err := ast.Walk(doc, r.render); err != nil {
+		return "", "", fmt.Errorf("rendering storage format: %w", err)
+	}
+	if err := r.w.Flush(); err != nil {
+		return "", "", fmt.Errorf("rendering storage format: %w", err)
+	}
+
+	return title, buf.String(), nil
+}
+
+type storageRenderer struct {
+	src    []byte
+	w      *bufio.Writer
+	taskID int
+	alerts map[ast.Node]bool
+}
+
+func (r *storageRenderer) render(n ast.Node, entering bool) (ast.WalkStatus, error) {
+	switch n := n.(type) {
+	case *ast.Document:
+		// nothing to emit
+
+	case *ast.Heading:
+		if entering {
+			fmt.Fprintf(r.w, "<h%d>", n.Level)
+		} else {
+			fmt.Fprintf(r.w, "</h%d>\n", n.Level)
+		}
+
+	case *ast.Paragraph:
+		if entering {
+			r.w.WriteString("<p>")
+		} else {
+			r.w.WriteString("</p>\n")
+		}
+
+	case *ast.TextBlock:
+		// Tight list items render their text without a paragraph wrapper.
+
+	case *ast.ThematicBreak:
+		if entering {
+			r.w.WriteString("<hr />\n")
+		}
+
+	case *ast.FencedCodeBlock:
+		if entering {
+			r.writeCodeMacro(string(n.Language(r.src)), n.Lines())
+		}
+		return ast.WalkSkipChildren, nil
+
+	case *ast.CodeBlock:
+		if entering {
+			r.writeCodeMacro("", n.Lines())
+		}
+		return ast.WalkSkipChildren, nil
+
+	case *ast.Blockquote:
+		if entering {
+			return r.renderBlockquote(n)
+		}
+		if !r.alerts[n] {
+			r.w.WriteString("</blockquote>\n")
+		}
+
+	case *ast.List:
+		if isTaskList(n) {
+			if entering {
+				r.w.WriteString("<ac:task-list>\n")
+			} else {
+				r.w.WriteString("</ac:task-list>\n")
+			}
+			break
+		}
+		tag := "ul"
+		if n.IsOrdered() {
+			tag = "ol"
+		}
+		if entering {
+			if n.IsOrdered() && n.Start > 1 {
+				fmt.Fprintf(r.w, "<%s start=\"%d\">\n", tag, n.Start)
+			} else {
+				fmt.Fprintf(r.w, "<%s>\n", tag)
+			}
+		} else {
+			fmt.Fprintf(r.w, "</%s>\n", tag)
+		}
+
+	case *ast.ListItem:
+		if isTaskList(n.Parent().(*ast.List)) {
+			if entering {
+				r.taskID++
+				status := "incomplete"
+				if taskCheckBox(n).IsChecked {
+					status = "complete"
+				}
+				fmt.Fprintf(r.w, "<ac:task>\n<ac:task-id>%d</ac:task-id>\n<ac:task-status>%s</ac:task-status>\n<ac:task-body>", r.taskID, status)
+			} else {
+				r.w.WriteString("</ac:task-body>\n</ac:task>\n")
+			}
+			break
+		}
+		if entering {
+			r.w.WriteString("<li>")
+		} else {
+			r.w.WriteString("</li>\n")
+		}
+
+	case *ast.HTMLBlock:
+		if entering {
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				r.w.Write(seg.Value(r.src))
+			}
+			if n.HasClosure() {
+				r.w.Write(n.ClosureLine.Value(r.src))
+			}
+		}
+		return ast.WalkSkipChildren, nil
+
+	case *east.Table:
+		if entering {
+			r.w.WriteString("<table>\n<tbody>\n")
+		} else {
+			r.w.WriteString("</tbody>\n</table>\n")
+		}
+
+	case *east.TableHeader, *east.TableRow:
+		if entering {
+			r.w.WriteString("<tr>")
+		} else {
+			r.w.WriteString("</tr>\n")
+		}
+
+	case *east.TableCell:
+		tag := "td"
+		if _, ok := n.Parent().(*east.TableHeader); ok {
+			tag = "th"
+		}
+		if entering {
+			r.w.WriteString("<" + tag)
+			if n.Alignment != east.AlignNone {
+				fmt.Fprintf(r.w, " style=\"text-align: %s;\"", n.Alignment)
+			}
+			r.w.WriteString(">")
+		} else {
+			r.w.WriteString("</" + tag + ">")
+		}
+
+	case *east.TaskCheckBox:
+		// In a task list the box is rendered as the ac:task status by the
+		// enclosing list item. Lists mixing tasks and plain items stay
+		// lists, so the box is kept as text.
+		if !entering {
+			break
+		}
+		if list, ok := n.Parent().Parent().Parent().(*ast.List); ok && isTaskList(list) {
+			break
+		}
+		if n.IsChecked {
+			r.w.WriteString("[x] ")
+		} else {
+			r.w.WriteString("[ ] ")
+		}
+
+	case *ast.Text:
+		if !entering {
+			break
+		}
+		value := n.Segment.Value(r.src)
+		if n.IsRaw() {
+			html.DefaultWriter.RawWrite(r.w, value)
+		} else {
+			html.DefaultWriter.Write(r.w, value)
+		}
+		if n.HardLineBreak() {
+			r.w.WriteString("<br />")
+		} else if n.SoftLineBreak() {
+			r.w.WriteString("\n")
+		}
+
+	case *ast.String:
+		if entering {
+			if n.IsCode() {
+				html.DefaultWriter.RawWrite(r.w, n.Value)
+			} else {
+				html.DefaultWriter.Write(r.w, n.Value)
+			}
+		}
+
+	case *ast.Emphasis:
+		tag := "em"
+		if n.Level == 2 {
+			tag = "strong"
+		}
+		if entering {
+			r.w.WriteString("<" + tag + ">")
+		} else {
+			r.w.WriteString("</" + tag + ">")
+		}
+
+	case *east.Strikethrough:
+		if entering {
+			r.w.WriteString("<del>")
+		} else {
+			r.w.WriteString("</del>")
+		}
+
+	case *ast.CodeSpan:
+		if entering {
+			r.w.WriteString("<code>")
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					html.DefaultWriter.RawWrite(r.w, t.Segment.Value(r.src))
+				}
+			}
+			r.w.WriteString("</code>")
+		}
+		return ast.WalkSkipChildren, nil
+
+	case *ast.Link:
+		if entering {
+			fmt.Fprintf(r.w, "<a href=\"%s\">", escapeAttr(n.Destination))
+		} else {
+			r.w.WriteString("</a>")
+		}
+
+	case *ast.AutoLink:
+		if entering {
+			url := n.URL(r.src)
+			if n.AutoLinkType == ast.AutoLinkEmail && !bytes.HasPrefix(bytes.ToLower(url), []byte("mailto:")) {
+				url = append([]byte("mailto:"), url...)
+			}
+			fmt.Fprintf(r.w, "<a href=\"%s\">", escapeAttr(url))
+			html.DefaultWriter.Write(r.w, n.Label(r.src))
+			r.w.WriteString("</a>")
+		}
+		return ast.WalkSkipChildren, nil
+
+	case *ast.Image:
+		if entering {
+			alt := plainText(n, r.src)
+			r.w.WriteString("<ac:image")
+			if len(alt) > 0 {
+				fmt.Fprintf(r.w, " ac:alt=\"%s\"", escapeAttr(alt))
+			}
+			fmt.Fprintf(r.w, "><ri:url ri:value=\"%s\" /></ac:image>", escapeAttr(n.Destination))
+		}
+		return ast.WalkSkipChildren, nil
+
+	case *ast.RawHTML:
+		if entering {
+			segs := n.Segments
+			for i := 0; i < segs.Len(); i++ {
+				seg := segs.At(i)
+				r.w.Write(seg.Value(r.src))
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	}
+
+	return ast.WalkContinue, nil
+}
+
+// renderBlockquote emits a GFM alert ("> [!NOTE]") as the matching
+// Confluence panel macro, and any other blockquote as-is.
+func (r *storageRenderer) renderBlockquote(n *ast.Blockquote) (ast.WalkStatus, error) {
+	para, ok := n.FirstChild().(*ast.Paragraph)
+	if !ok || para.Lines().Len() == 0 {
+		r.w.WriteString("<blockquote>\n")
+		return ast.WalkContinue, nil
+	}
+
+	first := para.Lines().At(0)
+	m := alertPattern.FindSubmatch(first.Value(r.src))
+	if m == nil {
+		r.w.WriteString("<blockquote>\n")
+		return ast.WalkContinue, nil
+	}
+
+	// Drop the marker line from the first paragraph, and the paragraph
+	// itself if nothing else is left in it.
+	for c := para.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		if t, ok := c.(*ast.Text); ok && t.Segment.Start < first.Stop {
+			para.RemoveChild(para, c)
+		}
+		c = next
+	}
+	if para.FirstChild() == nil {
+		n.RemoveChild(n, para)
+	}
+
+	r.alerts[n] = true
+	fmt.Fprintf(r.w, "<ac:structured-macro ac:name=\"%s\">\n<ac:rich-text-body>\n", alertMacros[string(m[1])])
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if err := ast.Walk(c, r.render); err != nil {
+			return ast.WalkStop, err
+		}
+	}
+	r.w.WriteString("</ac:rich-text-body>\n</ac:structured-macro>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *storageRenderer) writeCodeMacro(language string, lines *text.Segments) {
+	var code strings.Builder
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		code.Write(seg.Value(r.src))
+	}
+
+	r.w.WriteString("<ac:structured-macro ac:name=\"code\">")
+	if language != "" {
+		fmt.Fprintf(r.w, "<ac:parameter ac:name=\"language\">%s</ac:parameter>", escapeAttr([]byte(language)))
+	}
+	r.w.WriteString("<ac:plain-text-body><![CDATA[")
+	r.w.WriteString(strings.ReplaceAll(strings.TrimSuffix(code.String(), "\n"), "]]>", "]]]]><![CDATA[>"))
+	r.w.WriteString("]]></ac:plain-text-body></ac:structured-macro>\n")
+}
+
+// isTaskList reports whether every item in the list starts with a GFM
+// task checkbox.
+func isTaskList(list *ast.List) bool {
+	if list.FirstChild() == nil {
+		return false
+	}
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		if taskCheckBox(item) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func taskCheckBox(item ast.Node) *east.TaskCheckBox {
+	block := item.FirstChild()
+	if block == nil {
+		return nil
+	}
+	box, _ := block.FirstChild().(*east.TaskCheckBox)
+	return box
+}
+
+// plainText returns the concatenated text content of a node.
+func plainText(n ast.Node, src []byte) []byte {
+	var buf bytes.Buffer
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			buf.Write(c.Segment.Value(src))
+			if c.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(c.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.Bytes()
+}
+
+func escapeAttr(value []byte) string {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	html.DefaultWriter.Write(w, value)
+	w.Flush()
+	return buf.String()
+}
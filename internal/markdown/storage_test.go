@@ -0,0 +1,112 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "headings and inline formatting",
+			input: "## Setup\n\nRun **this** and *that* & `a<b`\n",
+			want: []string{
+				"<h2>Setup</h2>",
+				"<strong>this</strong>",
+				"<em>that</em>",
+				"&amp;",
+				"<code>a&lt;b</code>",
+			},
+		},
+		{
+			name:  "lists",
+			input: "- one\n- two\n\n3. three\n4. four\n",
+			want: []string{
+				"<ul>\n<li>one</li>\n<li>two</li>\n</ul>",
+				`<ol start="3">`,
+				"<li>three</li>",
+			},
+		},
+		{
+			name:  "table with alignment",
+			input: "| A | B |\n|:--|--:|\n| 1 | 2 |\n",
+			want: []string{
+				`<th style="text-align: left;">A</th>`,
+				`<td style="text-align: right;">2</td>`,
+			},
+		},
+		{
+			name:  "code fence becomes code macro",
+			input: "```go\nfmt.Println(\"]]>\")\n```\n",
+			want: []string{
+				`<ac:structured-macro ac:name="code">`,
+				`<ac:parameter ac:name="language">go</ac:parameter>`,
+				`<![CDATA[fmt.Println("]]]]><![CDATA[>")]]>`,
+			},
+		},
+		{
+			name:  "task list",
+			input: "- [ ] todo\n- [x] done\n",
+			want: []string{
+				"<ac:task-list>",
+				"<ac:task-status>incomplete</ac:task-status>\n<ac:task-body>todo</ac:task-body>",
+				"<ac:task-status>complete</ac:task-status>\n<ac:task-body>done</ac:task-body>",
+			},
+			notWant: []string{"<ul>", "[x]"},
+		},
+		{
+			name:    "list mixing tasks and plain items",
+			input:   "- [ ] todo\n- [x] done\n- note\n",
+			want:    []string{"<ul>", "<li>[ ] todo</li>", "<li>[x] done</li>", "<li>note</li>"},
+			notWant: []string{"<ac:task"},
+		},
+		{
+			name:    "GFM alert becomes panel",
+			input:   "> [!WARNING]\n> Be careful\n",
+			want:    []string{`<ac:structured-macro ac:name="warning">`, "<p>Be careful</p>"},
+			notWant: []string{"[!WARNING]", "<blockquote>"},
+		},
+		{
+			name:  "plain blockquote",
+			input: "> quoted\n",
+			want:  []string{"<blockquote>\n<p>quoted</p>\n</blockquote>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, result, err := ToStorage([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result, want) {
+					t.Errorf("expected %q in result, got: %s", want, result)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(result, notWant) {
+					t.Errorf("did not expect %q in result, got: %s", notWant, result)
+				}
+			}
+		})
+	}
+}
+
+func TestToStorage_Title(t *testing.T) {
+	title, result, err := ToStorage([]byte("# My Page\n\nBody text\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if title != "My Page" {
+		t.Errorf("expected title %q, got %q", "My Page", title)
+	}
+	if strings.Contains(result, "<h1>") {
+		t.Errorf("expected title heading to be removed from body, got: %s", result)
+	}
+}
@@ -0,0 +1,413 @@
+package markdown
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/justinabrahms/confluence-md/internal/storage"
+)
+
+// TableStyle selects how tables that can't be written as GFM pipe tables
+// are rendered.
+type TableStyle string
+
+const (
+	// TableHTML renders complex tables as plain HTML tables.
+	TableHTML TableStyle = "html"
+	// TableGrid renders complex tables as Pandoc-style grid tables.
+	TableGrid TableStyle = "grid"
+)
+
+// ParseTableStyle validates a --tables flag value.
+func ParseTableStyle(s string) (TableStyle, error) {
+	switch t := TableStyle(s); t {
+	case TableHTML, TableGrid:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown table style %q (expected html or grid)", s)
+}
+
+// WithTableStyle sets how tables with merged cells, header columns or
+// block content in cells are rendered. Simple tables are always written
+// as GFM pipe tables.
+func WithTableStyle(s TableStyle) Option {
+	return func(c *Converter) {
+		c.tableStyle = s
+	}
+}
+
+type tableCell struct {
+	node     *storage.Node
+	header   bool
+	rowspan  int
+	colspan  int
+	markdown string
+	// block is true when the cell holds content a pipe table can't, such
+	// as lists, code blocks or nested tables.
+	block bool
+	// rendered is true when the cell's content only exists as Markdown,
+	// as for block macros, nested tables and ADF cells.
+	rendered bool
+}
+
+// blockCellElements are elements that can't be written on a single line of
+// a pipe table cell.
+var blockCellElements = []string{"ul", "ol", "pre", "blockquote", "h1", "h2", "h3", "h4", "h5", "h6", "hr"}
+
+// renderTables replaces every table in n's subtree with a placeholder for
+// its rendered Markdown. Nested tables are rendered first, so they end up
+// as block content of the enclosing table's cells.
+func (c *Converter) renderTables(n *storage.Node, ph *placeholders) error {
+	tables := n.FindAll("table")
+	for i := len(tables) - 1; i >= 0; i-- {
+		md, err := c.renderTable(tables[i], ph)
+		if err != nil {
+			return err
+		}
+		p := storage.NewElement("p")
+		p.AppendChild(storage.NewText(ph.add(md, false)))
+		tables[i].ReplaceWith(p)
+	}
+	return nil
+}
+
+func (c *Converter) renderTable(table *storage.Node, ph *placeholders) (string, error) {
+	var rows [][]*tableCell
+	for _, tr := range table.FindAll("tr") {
+		var row []*tableCell
+		for _, cell := range tr.Children {
+			if !cell.Is("td") && !cell.Is("th") {
+				continue
+			}
+			md, err := c.htmlToMarkdown(cell.InnerHTML())
+			if err != nil {
+				return "", err
+			}
+			tc := &tableCell{
+				node:     cell,
+				header:   cell.Is("th"),
+				rowspan:  spanAttr(cell, "rowspan"),
+				colspan:  spanAttr(cell, "colspan"),
+				rendered: ph.hasBlock(md),
+				markdown: strings.TrimSpace(ph.expand(md)),
+			}
+			// Macros directly in a cell render as blocks, but one that
+			// renders as a single line, like a status, fits a pipe table
+			tc.block = tc.rendered && strings.Contains(tc.markdown, "\n")
+			for _, name := range blockCellElements {
+				if cell.Find(name) != nil {
+					tc.block = true
+				}
+			}
+			row = append(row, tc)
+		}
+		rows = append(rows, row)
+	}
+	return c.formatTable(rows), nil
+}
+
+// formatTable writes a table as a GFM pipe table when it fits one, and in
+// the converter's table style otherwise.
+func (c *Converter) formatTable(rows [][]*tableCell) string {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	header := isHeaderRow(rows[0])
+	if isSimpleTable(rows, header) {
+		return renderPipeTable(rows, header)
+	}
+	if c.tableStyle == TableGrid {
+		return renderGridTable(rows, header)
+	}
+	return renderHTMLTable(rows)
+}
+
+func spanAttr(n *storage.Node, name string) int {
+	if v, err := strconv.Atoi(n.Attr(name)); err == nil && v > 1 {
+		return v
+	}
+	return 1
+}
+
+func isHeaderRow(row []*tableCell) bool {
+	for _, cell := range row {
+		if !cell.header {
+			return false
+		}
+	}
+	return len(row) > 0
+}
+
+// isSimpleTable reports whether a table fits a GFM pipe table: no merged
+// cells, no block content, and header cells only as a full first row.
+func isSimpleTable(rows [][]*tableCell, header bool) bool {
+	for i, row := range rows {
+		for _, cell := range row {
+			if cell.rowspan > 1 || cell.colspan > 1 || cell.block {
+				return false
+			}
+			if cell.header && !(i == 0 && header) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func renderPipeTable(rows [][]*tableCell, header bool) string {
+	var lines [][]string
+	for _, row := range rows {
+		var cells []string
+		for _, cell := range row {
+			cells = append(cells, pipeCell(cell.markdown))
+		}
+		lines = append(lines, cells)
+	}
+	if header {
+		return pipeTable(lines[0], lines[1:])
+	}
+	return pipeTable(nil, lines)
+}
+
+// pipeCell puts a cell's Markdown on one line, turning paragraph and line
+// breaks into <br>.
+func pipeCell(md string) string {
+	var parts []string
+	for _, line := range strings.Split(md, "\n") {
+		line = strings.TrimRight(line, " ")
+		line = strings.TrimSuffix(line, `\`)
+		if strings.TrimSpace(line) != "" {
+			parts = append(parts, strings.TrimSpace(line))
+		}
+	}
+	// html-to-markdown escapes pipes already; macro output may not
+	cell := strings.ReplaceAll(strings.Join(parts, "<br>"), `\|`, "|")
+	return strings.ReplaceAll(cell, "|", `\|`)
+}
+
+// renderHTMLTable writes a table as HTML with Confluence's presentational
+// attributes removed, keeping merged cells intact.
+func renderHTMLTable(rows [][]*tableCell) string {
+	var b strings.Builder
+	b.WriteString("<table>\n")
+	for _, row := range rows {
+		b.WriteString("  <tr>\n")
+		for _, cell := range row {
+			tag := "td"
+			if cell.header {
+				tag = "th"
+			}
+			b.WriteString("    <" + tag)
+			if cell.rowspan > 1 {
+				b.WriteString(fmt.Sprintf(` rowspan="%d"`, cell.rowspan))
+			}
+			if cell.colspan > 1 {
+				b.WriteString(fmt.Sprintf(` colspan="%d"`, cell.colspan))
+			}
+			if cell.rendered {
+				// Markdown inside an HTML block needs blank lines around it
+				b.WriteString(">\n\n" + cell.markdown + "\n\n</" + tag + ">\n")
+			} else {
+				b.WriteString(">" + htmlCellContent(cell.node) + "</" + tag + ">\n")
+			}
+		}
+		b.WriteString("  </tr>\n")
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
+
+// htmlCellContent returns a cell's inner HTML without presentational
+// attributes, a lone wrapping paragraph, or blank lines (which would end
+// the HTML block in Markdown).
+func htmlCellContent(cell *storage.Node) string {
+	cell.Walk(func(n *storage.Node) bool {
+		var attrs []storage.Attr
+		for _, a := range n.Attrs {
+			if a.Name == "class" || a.Name == "style" || strings.HasPrefix(a.Name, "data-") || strings.HasPrefix(a.Name, "ac:") {
+				continue
+			}
+			attrs = append(attrs, a)
+		}
+		n.Attrs = attrs
+		return true
+	})
+
+	var elements []*storage.Node
+	for _, c := range cell.Children {
+		if c.Type == storage.ElementNode || strings.TrimSpace(c.Data) != "" {
+			elements = append(elements, c)
+		}
+	}
+	if len(elements) == 1 && elements[0].Is("p") {
+		elements[0].Unwrap()
+	}
+
+	lines := strings.Split(strings.TrimSpace(cell.InnerHTML()), "\n")
+	var kept []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// renderGridTable writes a table as a Pandoc grid table, which supports
+// merged cells and block content.
+func renderGridTable(rows [][]*tableCell, header bool) string {
+	type placed struct {
+		cell     *tableCell
+		row, col int
+		lines    []string
+	}
+
+	// Lay cells out on a grid, skipping slots taken by rowspans above.
+	var cells []placed
+	occupied := map[[2]int]bool{}
+	nrows, ncols := len(rows), 0
+	for r, row := range rows {
+		col := 0
+		for _, cell := range row {
+			for occupied[[2]int{r, col}] {
+				col++
+			}
+			for dr := 0; dr < cell.rowspan; dr++ {
+				for dc := 0; dc < cell.colspan; dc++ {
+					occupied[[2]int{r + dr, col + dc}] = true
+				}
+			}
+			cells = append(cells, placed{cell: cell, row: r, col: col, lines: strings.Split(cell.markdown, "\n")})
+			nrows = max(nrows, r+cell.rowspan)
+			ncols = max(ncols, col+cell.colspan)
+			col += cell.colspan
+		}
+	}
+	for slot := range occupied {
+		ncols = max(ncols, slot[1]+1)
+	}
+
+	// Size columns and rows: single cells first, then widen the last
+	// column or row of a merged cell if its content needs more room.
+	widths := make([]int, ncols)
+	heights := make([]int, nrows)
+	for i := range widths {
+		widths[i] = 3
+	}
+	for i := range heights {
+		heights[i] = 1
+	}
+	for pass := 0; pass < 2; pass++ {
+		for _, p := range cells {
+			width := 0
+			for _, line := range p.lines {
+				width = max(width, len([]rune(line))+2)
+			}
+			spanned := p.cell.colspan > 1 || p.cell.rowspan > 1
+			if (pass == 0) == spanned {
+				continue
+			}
+
+			available := p.cell.colspan - 1
+			for c := p.col; c < p.col+p.cell.colspan; c++ {
+				available += widths[c]
+			}
+			if width > available {
+				widths[p.col+p.cell.colspan-1] += width - available
+			}
+
+			available = p.cell.rowspan - 1
+			for r := p.row; r < p.row+p.cell.rowspan; r++ {
+				available += heights[r]
+			}
+			if len(p.lines) > available {
+				heights[p.row+p.cell.rowspan-1] += len(p.lines) - available
+			}
+		}
+	}
+
+	xs := make([]int, ncols+1)
+	for c, w := range widths {
+		xs[c+1] = xs[c] + w + 1
+	}
+	ys := make([]int, nrows+1)
+	for r, h := range heights {
+		ys[r+1] = ys[r] + h + 1
+	}
+
+	canvas := make([][]rune, ys[nrows]+1)
+	for y := range canvas {
+		canvas[y] = []rune(strings.Repeat(" ", xs[ncols]+1))
+	}
+	draw := func(y, x int, r rune) {
+		switch cur := canvas[y][x]; {
+		case cur == '+':
+		case cur != ' ' && cur != r:
+			canvas[y][x] = '+'
+		default:
+			canvas[y][x] = r
+		}
+	}
+
+	for _, p := range cells {
+		x0, x1 := xs[p.col], xs[p.col+p.cell.colspan]
+		y0, y1 := ys[p.row], ys[p.row+p.cell.rowspan]
+		for x := x0; x <= x1; x++ {
+			draw(y0, x, '-')
+			draw(y1, x, '-')
+		}
+		for y := y0; y <= y1; y++ {
+			draw(y, x0, '|')
+			draw(y, x1, '|')
+		}
+		for _, corner := range [][2]int{{y0, x0}, {y0, x1}, {y1, x0}, {y1, x1}} {
+			canvas[corner[0]][corner[1]] = '+'
+		}
+		for i, line := range p.lines {
+			copy(canvas[y0+1+i][x0+2:], []rune(line))
+		}
+	}
+
+	// Slots no cell covers (ragged rows) still need their borders.
+	for r := 0; r < nrows; r++ {
+		for c := 0; c < ncols; c++ {
+			if occupied[[2]int{r, c}] {
+				continue
+			}
+			for x := xs[c]; x <= xs[c+1]; x++ {
+				draw(ys[r], x, '-')
+				draw(ys[r+1], x, '-')
+			}
+			for y := ys[r]; y <= ys[r+1]; y++ {
+				draw(y, xs[c], '|')
+				draw(y, xs[c+1], '|')
+			}
+			for _, corner := range [][2]int{{ys[r], xs[c]}, {ys[r], xs[c+1]}, {ys[r+1], xs[c]}, {ys[r+1], xs[c+1]}} {
+				canvas[corner[0]][corner[1]] = '+'
+			}
+		}
+	}
+
+	// The header separator can't cross a cell merged into the body.
+	for _, p := range cells {
+		if p.row == 0 && p.cell.rowspan > 1 {
+			header = false
+		}
+	}
+	if header && nrows > 1 {
+		line := canvas[ys[1]]
+		for x, r := range line {
+			if r == '-' {
+				line[x] = '='
+			}
+		}
+	}
+
+	lines := make([]string, len(canvas))
+	for y, line := range canvas {
+		lines[y] = strings.TrimRight(string(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,85 @@
+package markdown
+
+import "testing"
+
+func TestTables(t *testing.T) {
+	merged := `<table><tbody><tr><th><p>Name</p></th><th colspan="2"><p>Details</p></th></tr>` +
+		`<tr><td rowspan="2"><p>Alpha</p></td><td class="x"><ul><li>one</li><li>two</li></ul></td><td><p>c</p></td></tr>` +
+		`<tr><td><p>d</p></td><td><ac:structured-macro ac:name="code"><ac:plain-text-body><![CDATA[x := 1]]></ac:plain-text-body></ac:structured-macro></td></tr></tbody></table>`
+
+	tests := []struct {
+		name  string
+		style TableStyle
+		input string
+		want  string
+	}{
+		{
+			name:  "single-line macros in cells",
+			style: TableHTML,
+			input: `<table><tbody><tr><th>Task</th><th>State</th></tr><tr><td>Deploy</td><td><ac:structured-macro ac:name="status"><ac:parameter ac:name="title">Done</ac:parameter></ac:structured-macro></td></tr></tbody></table>`,
+			want:  "| Task | State |\n| --- | --- |\n| Deploy | [DONE] |",
+		},
+		{
+			name:  "simple table with line breaks",
+			style: TableHTML,
+			input: `<table data-layout="default"><colgroup><col style="width: 10px" /></colgroup><tbody><tr><th><p>A</p></th><th><p>B</p></th></tr><tr><td><p>x</p><p>y<br />w</p></td><td>z | q</td></tr></tbody></table>`,
+			want:  "| A | B |\n| --- | --- |\n| x<br>y<br>w | z \\| q |",
+		},
+		{
+			name:  "table without header row",
+			style: TableHTML,
+			input: `<table><tbody><tr><td>a</td><td>b</td></tr></tbody></table>`,
+			want:  "|  |  |\n| --- | --- |\n| a | b |",
+		},
+		{
+			name:  "header column falls back to HTML",
+			style: TableHTML,
+			input: `<table><tbody><tr><th>Key</th><td style="color: red"><p>value</p></td></tr></tbody></table>`,
+			want:  "<table>\n  <tr>\n    <th>Key</th>\n    <td>value</td>\n  </tr>\n</table>",
+		},
+		{
+			name:  "merged cells as HTML",
+			style: TableHTML,
+			input: merged,
+			want: "<table>\n" +
+				"  <tr>\n    <th>Name</th>\n    <th colspan=\"2\">Details</th>\n  </tr>\n" +
+				"  <tr>\n    <td rowspan=\"2\">Alpha</td>\n    <td><ul><li>one</li><li>two</li></ul></td>\n    <td>c</td>\n  </tr>\n" +
+				"  <tr>\n    <td>d</td>\n    <td>\n\n```\nx := 1\n```\n\n</td>\n  </tr>\n" +
+				"</table>",
+		},
+		{
+			name:  "merged cells as grid table",
+			style: TableGrid,
+			input: merged,
+			want: "+-------+----------------+\n" +
+				"| Name  | Details        |\n" +
+				"+=======+=======+========+\n" +
+				"| Alpha | - one | c      |\n" +
+				"|       | - two |        |\n" +
+				"|       +-------+--------+\n" +
+				"|       | d     | ```    |\n" +
+				"|       |       | x := 1 |\n" +
+				"|       |       | ```    |\n" +
+				"+-------+-------+--------+",
+		},
+		{
+			name:  "nested table",
+			style: TableGrid,
+			input: `<table><tbody><tr><td><table><tbody><tr><td>inner</td></tr></tbody></table></td><td>outer</td></tr></tbody></table>`,
+			want: "+-----------+-------+\n" +
+				"| |  |      | outer |\n" +
+				"| | --- |   |       |\n" +
+				"| | inner | |       |\n" +
+				"+-----------+-------+",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertStorage(t, NewConverter(WithTableStyle(tt.style)), tt.input)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+// Package storage parses Confluence storage format into a small DOM.
+//
+// Storage format is XHTML extended with elements and attributes in the
+// "ac:" (macros, tasks, links) and "ri:" (resource identifiers)
+// namespaces, whose prefixes are never declared. Macro bodies are commonly
+// wrapped in CDATA sections. Names are kept in their prefixed form, e.g.
+// "ac:structured-macro" or "ri:content-title".
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type NodeType int
+
+const (
+	// DocumentNode is the root of a parsed fragment; it has no name.
+	DocumentNode NodeType = iota
+	ElementNode
+	TextNode
+	CommentNode
+)
+
+type Attr struct {
+	Name  string
+	Value string
+}
+
+// Node is an element, text run or comment in a storage document.
+type Node struct {
+	Type     NodeType
+	Name     string // element name, e.g. "p" or "ac:task"
+	Attrs    []Attr
+	Data     string // text or comment content; CDATA is decoded to text
+	Parent   *Node
+	Children []*Node
+}
+
+// voidElements never have content and are rendered without an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// Parse parses a storage-format fragment. It is lenient in the same way
+// browsers are: HTML entities are resolved, void elements such as <br>
+// need not be self-closed, an end tag closes the elements left open inside
+// its element, end tags with no open element are ignored, and a "<" that
+// can't start a tag is read as text.
+func Parse(src string) (*Node, error) {
+	d := xml.NewDecoder(strings.NewReader("<storage-root>" + escapeStrayLT(src) + "</storage-root>"))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	root := &Node{Type: DocumentNode}
+	cur := root
+	started := false
+	for {
+		// RawToken doesn't require end tags to match, so unbalanced
+		// markup is repaired here instead of failing the whole page.
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing storage format: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !started {
+				started = true
+				continue
+			}
+			el := &Node{Type: ElementNode, Name: qualify(t.Name)}
+			for _, a := range t.Attr {
+				el.Attrs = append(el.Attrs, Attr{Name: qualify(a.Name), Value: a.Value})
+			}
+			cur.AppendChild(el)
+			if !voidElements[el.Name] {
+				cur = el
+			}
+		case xml.EndElement:
+			name := qualify(t.Name)
+			for open := cur; open != root; open = open.Parent {
+				if open.Name == name {
+					cur = open.Parent
+					break
+				}
+			}
+		case xml.CharData:
+			cur.AppendChild(&Node{Type: TextNode, Data: string(t)})
+		case xml.Comment:
+			cur.AppendChild(&Node{Type: CommentNode, Data: string(t)})
+		}
+	}
+
+	return root, nil
+}
+
+// escapeStrayLT escapes each "<" in src that can't start a tag, comment,
+// CDATA section or processing instruction, as in "1 < 2". CDATA sections
+// and comments are copied unchanged.
+func escapeStrayLT(src string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(src, '<')
+		if i < 0 {
+			b.WriteString(src)
+			return b.String()
+		}
+		b.WriteString(src[:i])
+		src = src[i:]
+
+		if end := sectionEnd(src); end > 0 {
+			b.WriteString(src[:end])
+			src = src[end:]
+			continue
+		}
+		if len(src) > 1 && startsTag(src[1]) {
+			b.WriteByte('<')
+		} else {
+			b.WriteString("&lt;")
+		}
+		src = src[1:]
+	}
+}
+
+// sectionEnd returns the length of the CDATA section or comment src starts
+// with, or 0 if it starts with neither.
+func sectionEnd(src string) int {
+	for _, section := range [][2]string{{"<![CDATA[", "]]>"}, {"<!--", "-->"}} {
+		if !strings.HasPrefix(src, section[0]) {
+			continue
+		}
+		if end := strings.Index(src, section[1]); end >= 0 {
+			return end + len(section[1])
+		}
+		return len(src)
+	}
+	return 0
+}
+
+// startsTag reports whether c may follow "<" at the start of markup.
+func startsTag(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':' || c == '/' || c == '!' || c == '?' || c >= 0x80
+}
+
+func qualify(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// NewElement creates a detached element node.
+func NewElement(name string, attrs ...Attr) *Node {
+	return &Node{Type: ElementNode, Name: name, Attrs: attrs}
+}
+
+// NewText creates a detached text node.
+func NewText(text string) *Node {
+	return &Node{Type: TextNode, Data: text}
+}
+
+// Attr returns the value of the named attribute, or "" if it is absent.
+func (n *Node) Attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Is reports whether n is an element with the given name.
+func (n *Node) Is(name string) bool {
+	return n != nil && n.Type == ElementNode && n.Name == name
+}
+
+// AppendChild adds child as the last child of n.
+func (n *Node) AppendChild(child *Node) {
+	child.Parent = n
+	n.Children = append(n.Children, child)
+}
+
+// Child returns the first direct child element with the given name.
+func (n *Node) Child(name string) *Node {
+	for _, c := range n.Children {
+		if c.Is(name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Find returns the first descendant element with the given name, in
+// document order.
+func (n *Node) Find(name string) *Node {
+	for _, c := range n.Children {
+		if c.Is(name) {
+			return c
+		}
+		if found := c.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// FindAll returns every descendant element with the given name, in
+// document order.
+func (n *Node) FindAll(name string) []*Node {
+	var found []*Node
+	n.Walk(func(c *Node) bool {
+		if c != n && c.Is(name) {
+			found = append(found, c)
+		}
+		return true
+	})
+	return found
+}
+
+// Walk calls fn for n and its descendants in document order. Children are
+// skipped when fn returns false.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// ReplaceWith replaces n in its parent with the given nodes.
+func (n *Node) ReplaceWith(nodes ...*Node) {
+	parent := n.Parent
+	if parent == nil {
+		return
+	}
+	for i, c := range parent.Children {
+		if c != n {
+			continue
+		}
+		for _, r := range nodes {
+			r.Parent = parent
+		}
+		children := append([]*Node{}, parent.Children[:i]...)
+		children = append(children, nodes...)
+		parent.Children = append(children, parent.Children[i+1:]...)
+		n.Parent = nil
+		return
+	}
+}
+
+// Unwrap replaces n with its children.
+func (n *Node) Unwrap() {
+	n.ReplaceWith(n.Children...)
+}
+
+// Remove detaches n from its parent.
+func (n *Node) Remove() {
+	n.ReplaceWith()
+}
+
+// Text returns the concatenated text content of n and its descendants.
+func (n *Node) Text() string {
+	var b strings.Builder
+	n.Walk(func(c *Node) bool {
+		if c.Type == TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// HTML renders n as HTML. A DocumentNode renders only its children.
+func (n *Node) HTML() string {
+	var b strings.Builder
+	render(&b, n)
+	return b.String()
+}
+
+// InnerHTML renders the children of n as HTML.
+func (n *Node) InnerHTML() string {
+	var b strings.Builder
+	for _, c := range n.Children {
+		render(&b, c)
+	}
+	return b.String()
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+func render(b *strings.Builder, n *Node) {
+	switch n.Type {
+	case DocumentNode:
+		for _, c := range n.Children {
+			render(b, c)
+		}
+	case TextNode:
+		b.WriteString(textEscaper.Replace(n.Data))
+	case CommentNode:
+		b.WriteString("<!--" + n.Data + "-->")
+	case ElementNode:
+		b.WriteString("<" + n.Name)
+		for _, a := range n.Attrs {
+			b.WriteString(" " + a.Name + `="` + attrEscaper.Replace(a.Value) + `"`)
+		}
+		if voidElements[n.Name] && len(n.Children) == 0 {
+			b.WriteString(" />")
+			return
+		}
+		b.WriteString(">")
+		for _, c := range n.Children {
+			render(b, c)
+		}
+		b.WriteString("</" + n.Name + ">")
+	}
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain HTML",
+			input: `<p>This is <strong>bold</strong> &amp; plain</p>`,
+			want:  `<p>This is <strong>bold</strong> &amp; plain</p>`,
+		},
+		{
+			name:  "multiple roots",
+			input: `<h1>Title</h1><p>Body</p>`,
+			want:  `<h1>Title</h1><p>Body</p>`,
+		},
+		{
+			name:  "void elements without self-closing",
+			input: `<p>one<br>two</p><hr>`,
+			want:  `<p>one<br />two</p><hr />`,
+		},
+		{
+			name:  "HTML entities",
+			input: `<p>a&nbsp;b &lt;c&gt;</p>`,
+			want:  "<p>a b &lt;c&gt;</p>",
+		},
+		{
+			name:  "namespaced elements and attributes",
+			input: `<ac:link><ri:page ri:content-title="Home" ri:space-key="ENG" /></ac:link>`,
+			want:  `<ac:link><ri:page ri:content-title="Home" ri:space-key="ENG"></ri:page></ac:link>`,
+		},
+		{
+			name:  "CDATA is decoded to text",
+			input: `<ac:plain-text-body><![CDATA[if a < b && c]]></ac:plain-text-body>`,
+			want:  `<ac:plain-text-body>if a &lt; b &amp;&amp; c</ac:plain-text-body>`,
+		},
+		{
+			name:  "stray end tag inside element",
+			input: `<p>a</span></p>`,
+			want:  `<p>a</p>`,
+		},
+		{
+			name:  "stray end tag at top level",
+			input: `<p>x</p></div>`,
+			want:  `<p>x</p>`,
+		},
+		{
+			name:  "end tag closes open children",
+			input: `<p><em>a</p>b`,
+			want:  `<p><em>a</em></p>b`,
+		},
+		{
+			name:  "literal less-than",
+			input: `<p>1 < 2 <3 <</p>`,
+			want:  `<p>1 &lt; 2 &lt;3 &lt;</p>`,
+		},
+		{
+			name:  "less-than in CDATA after another CDATA",
+			input: `<ac:plain-text-body><![CDATA[a]]><![CDATA[b < c]]></ac:plain-text-body>`,
+			want:  `<ac:plain-text-body>ab &lt; c</ac:plain-text-body>`,
+		},
+		{
+			name:  "HTML link is void, ac:link is not",
+			input: `<link rel="x"><ac:link>y</ac:link>`,
+			want:  `<link rel="x" /><ac:link>y</ac:link>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := doc.HTML(); got != tt.want {
+				t.Errorf("HTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_Navigation(t *testing.T) {
+	doc, err := Parse(`<ac:structured-macro ac:name="code" ac:schema-version="1"><ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[x := 1]]></ac:plain-text-body></ac:structured-macro>`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	macro := doc.Find("ac:structured-macro")
+	if macro == nil {
+		t.Fatal("expected to find ac:structured-macro")
+	}
+	if got := macro.Attr("ac:name"); got != "code" {
+		t.Errorf("expected ac:name=code, got %q", got)
+	}
+	if got := macro.Child("ac:parameter").Text(); got != "go" {
+		t.Errorf("expected language parameter go, got %q", got)
+	}
+	if got := macro.Find("ac:plain-text-body").Text(); got != "x := 1" {
+		t.Errorf("expected CDATA body, got %q", got)
+	}
+
+	macro.ReplaceWith(NewText("replaced"))
+	if got := doc.HTML(); got != "replaced" {
+		t.Errorf("expected macro to be replaced, got %q", got)
+	}
+}
+
+func TestNode_Unwrap(t *testing.T) {
+	doc, err := Parse(`<p>a<span class="x">b<em>c</em></span>d</p>`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc.Find("span").Unwrap()
+	if got := doc.HTML(); got != `<p>ab<em>c</em>d</p>` {
+		t.Errorf("unexpected HTML after unwrap: %q", got)
+	}
+}